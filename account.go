@@ -0,0 +1,174 @@
+package ib
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"log"
+	"sort"
+
+	resty "github.com/go-resty/resty/v2"
+)
+
+// SummaryValue stores a single value of an account summary.
+type SummaryValue struct {
+	Amount    float64     `json:"amount"`
+	Currency  string      `json:"currency"`
+	IsNull    bool        `json:"isNull"`
+	Timestamp int64       `json:"timestamp"`
+	Value     interface{} `json:"value"`
+	Severity  int         `json:"severity"`
+}
+
+// AccountSummary stores the values of an account summary keyed by name, e.g. "netliquidation".
+// Keys suffixed with -c and -s hold the commodity and securities segment values.
+type AccountSummary map[string]SummaryValue
+
+// Summary retrieves the account summary of a portfolio
+func (p Portfolio) Summary() AccountSummary {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/portfolio/" + p.AccountID + "/summary")
+	if err != nil {
+		log.Panic(err)
+	}
+	summary := AccountSummary{}
+	err = json.Unmarshal(resp.Body(), &summary)
+	if err != nil {
+		log.Panic(err)
+	}
+	return summary
+}
+
+// amount returns the amount of a summary value or zero if it isn't present.
+func (as AccountSummary) amount(key string) float64 {
+	return as[key].Amount
+}
+
+// NetLiquidation returns the net liquidation value of the account.
+func (as AccountSummary) NetLiquidation() float64 {
+	return as.amount("netliquidation")
+}
+
+// BuyingPower returns the buying power of the account.
+func (as AccountSummary) BuyingPower() float64 {
+	return as.amount("buyingpower")
+}
+
+// TotalCash returns the total cash value of the account.
+func (as AccountSummary) TotalCash() float64 {
+	return as.amount("totalcashvalue")
+}
+
+// SettledCash returns the settled cash of the account.
+func (as AccountSummary) SettledCash() float64 {
+	return as.amount("settledcash")
+}
+
+// AvailableFunds returns the funds available for trading.
+func (as AccountSummary) AvailableFunds() float64 {
+	return as.amount("availablefunds")
+}
+
+// ExcessLiquidity returns the excess liquidity of the account.
+func (as AccountSummary) ExcessLiquidity() float64 {
+	return as.amount("excessliquidity")
+}
+
+// InitMargin returns the initial margin requirement of the account.
+func (as AccountSummary) InitMargin() float64 {
+	return as.amount("initmarginreq")
+}
+
+// MaintMargin returns the maintenance margin requirement of the account.
+func (as AccountSummary) MaintMargin() float64 {
+	return as.amount("maintmarginreq")
+}
+
+// GrossPositionValue returns the gross position value of the account.
+func (as AccountSummary) GrossPositionValue() float64 {
+	return as.amount("grosspositionvalue")
+}
+
+// LedgerEntry stores the balances of an account in a single currency.
+type LedgerEntry struct {
+	Currency                  string  `json:"currency"`
+	SecondKey                 string  `json:"secondkey"`
+	AcctCode                  string  `json:"acctcode"`
+	ExchangeRate              float64 `json:"exchangerate"`
+	CashBalance               float64 `json:"cashbalance"`
+	CashBalanceFXSegment      float64 `json:"cashbalancefxsegment"`
+	SettledCash               float64 `json:"settledcash"`
+	NetLiquidationValue       float64 `json:"netliquidationvalue"`
+	UnrealizedPnl             float64 `json:"unrealizedpnl"`
+	RealizedPnl               float64 `json:"realizedpnl"`
+	FuturesOnlyPnl            float64 `json:"futuresonlypnl"`
+	Interest                  float64 `json:"interest"`
+	Dividends                 float64 `json:"dividends"`
+	Funds                     float64 `json:"funds"`
+	MoneyFunds                float64 `json:"moneyfunds"`
+	StockMarketValue          float64 `json:"stockmarketvalue"`
+	StockOptionMarketValue    float64 `json:"stockoptionmarketvalue"`
+	FutureMarketValue         float64 `json:"futuremarketvalue"`
+	FutureOptionMarketValue   float64 `json:"futureoptionmarketvalue"`
+	CommodityMarketValue      float64 `json:"commoditymarketvalue"`
+	CorporateBondsMarketValue float64 `json:"corporatebondsmarketvalue"`
+	TBondsMarketValue         float64 `json:"tbondsmarketvalue"`
+	TBillsMarketValue         float64 `json:"tbillsmarketvalue"`
+	WarrantsMarketValue       float64 `json:"warrantsmarketvalue"`
+	IssuerOptionsMarketValue  float64 `json:"issueroptionsmarketvalue"`
+	Timestamp                 int64   `json:"timestamp"`
+	SessionID                 int     `json:"sessionid"`
+	Severity                  int     `json:"severity"`
+}
+
+// BaseCurrency is the ledger key of the row holding balances converted to the accounts base currency.
+const BaseCurrency = "BASE"
+
+// IsBase reports whether the entry holds the balances converted to the accounts base currency.
+// The actual base currency is stored in SecondKey.
+func (le LedgerEntry) IsBase() bool {
+	return le.Currency == BaseCurrency
+}
+
+// Ledger stores the balances of an account keyed by currency.
+type Ledger map[string]LedgerEntry
+
+// Ledger retrieves the per currency balances of a portfolio
+func (p Portfolio) Ledger() Ledger {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/portfolio/" + p.AccountID + "/ledger")
+	if err != nil {
+		log.Panic(err)
+	}
+	ledger := Ledger{}
+	err = json.Unmarshal(resp.Body(), &ledger)
+	if err != nil {
+		log.Panic(err)
+	}
+	return ledger
+}
+
+// Base returns the ledger entry in the accounts base currency.
+func (l Ledger) Base() LedgerEntry {
+	return l[BaseCurrency]
+}
+
+// Currencies returns the ledger entries in each individual currency, excluding the base currency row.
+// The entry in the accounts base currency comes first, the others follow in alphabetical order.
+func (l Ledger) Currencies() []LedgerEntry {
+	entries := make([]LedgerEntry, 0)
+	for key, entry := range l {
+		if key != BaseCurrency {
+			entries = append(entries, entry)
+		}
+	}
+	baseCurrency := l.Base().SecondKey
+	sort.Slice(entries, func(i, j int) bool {
+		if (entries[i].Currency == baseCurrency) != (entries[j].Currency == baseCurrency) {
+			return entries[i].Currency == baseCurrency
+		}
+		return entries[i].Currency < entries[j].Currency
+	})
+	return entries
+}
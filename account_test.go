@@ -0,0 +1,25 @@
+package ib
+
+import "testing"
+
+func TestLedgerCurrenciesOrder(t *testing.T) {
+	ledger := Ledger{
+		BaseCurrency: {Currency: BaseCurrency, SecondKey: "EUR"},
+		"USD":        {Currency: "USD"},
+		"CHF":        {Currency: "CHF"},
+		"EUR":        {Currency: "EUR"},
+		"AUD":        {Currency: "AUD"},
+	}
+	want := []string{"EUR", "AUD", "CHF", "USD"}
+	for n := 0; n < 5; n++ {
+		entries := ledger.Currencies()
+		if len(entries) != len(want) {
+			t.Fatalf("got %d entries, want %d", len(entries), len(want))
+		}
+		for i, entry := range entries {
+			if entry.Currency != want[i] {
+				t.Fatalf("entry %d is %s, want %s", i, entry.Currency, want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package ib
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"log"
+
+	resty "github.com/go-resty/resty/v2"
+)
+
+// AllocationMethod represents how a financial advisor allocation group splits orders across accounts.
+type AllocationMethod string
+
+const (
+	// AllocAvailableEquity allocates in proportion to each accounts available equity.
+	AllocAvailableEquity AllocationMethod = "A"
+	// AllocEqual allocates the same quantity to each account.
+	AllocEqual AllocationMethod = "E"
+	// AllocNetLiquidation allocates in proportion to each accounts net liquidation value.
+	AllocNetLiquidation AllocationMethod = "N"
+	// AllocCashQuantity allocates a cash amount to each account.
+	AllocCashQuantity AllocationMethod = "C"
+	// AllocPercentage allocates a percentage of the order to each account.
+	AllocPercentage AllocationMethod = "P"
+	// AllocRatios allocates in proportion to a ratio per account.
+	AllocRatios AllocationMethod = "R"
+	// AllocShares allocates a number of shares to each account.
+	AllocShares AllocationMethod = "S"
+)
+
+// AllocationGroupAccount stores the amount allocated to a single account of an allocation group.
+type AllocationGroupAccount struct {
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+}
+
+// AllocationGroup stores a financial advisor allocation group.
+type AllocationGroup struct {
+	Name          string                   `json:"name"`
+	PrevName      string                   `json:"prev_name,omitempty"`
+	Accounts      []AllocationGroupAccount `json:"accounts"`
+	DefaultMethod AllocationMethod         `json:"default_method"`
+}
+
+// AllocationGroupSummary stores the name and size of an allocation group.
+type AllocationGroupSummary struct {
+	Name             string           `json:"name"`
+	Size             int              `json:"size"`
+	AllocationMethod AllocationMethod `json:"allocation_method"`
+}
+
+type allocationGroupsResponse struct {
+	Data []AllocationGroupSummary `json:"data"`
+}
+
+// AllocationGroups retrieves all allocation groups of a financial advisor
+func AllocationGroups() []AllocationGroupSummary {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/iserver/account/allocation/group")
+	if err != nil {
+		log.Panic(err)
+	}
+	groups := allocationGroupsResponse{}
+	err = json.Unmarshal(resp.Body(), &groups)
+	if err != nil {
+		log.Panic(err)
+	}
+	return groups.Data
+}
+
+// GetAllocationGroup retrieves an allocation group by name
+func GetAllocationGroup(name string) AllocationGroup {
+	body := map[string]string{
+		"name": name,
+	}
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().SetBody(body).Post(base + "/api/iserver/account/allocation/group/single")
+	if err != nil {
+		log.Panic(err)
+	}
+	group := AllocationGroup{}
+	err = json.Unmarshal(resp.Body(), &group)
+	if err != nil {
+		log.Panic(err)
+	}
+	return group
+}
+
+// AddAllocationGroup creates an allocation group
+func AddAllocationGroup(group AllocationGroup) {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	_, err := client.R().SetBody(group).Post(base + "/api/iserver/account/allocation/group")
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// ModifyAllocationGroup modifies an allocation group.
+// Set PrevName to rename a group.
+func ModifyAllocationGroup(group AllocationGroup) {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	_, err := client.R().SetBody(group).Put(base + "/api/iserver/account/allocation/group")
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// DeleteAllocationGroup deletes an allocation group by name
+func DeleteAllocationGroup(name string) {
+	body := map[string]string{
+		"name": name,
+	}
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	_, err := client.R().SetBody(body).Delete(base + "/api/iserver/account/allocation/group/delete")
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// AllocationPresets stores the allocation defaults of a financial advisor.
+type AllocationPresets struct {
+	DefaultMethodForAll         string `json:"default_method_for_all"`
+	ProfilesEnabled             bool   `json:"profiles_enabled"`
+	GroupAutoClosePositions     bool   `json:"group_auto_close_positions"`
+	StrictCreditCheck           bool   `json:"strict_credit_check"`
+	GroupProportionalAllocation bool   `json:"group_proportional_allocation"`
+}
+
+// GetAllocationPresets retrieves the allocation presets of a financial advisor
+func GetAllocationPresets() AllocationPresets {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/iserver/account/allocation/presets")
+	if err != nil {
+		log.Panic(err)
+	}
+	presets := AllocationPresets{}
+	err = json.Unmarshal(resp.Body(), &presets)
+	if err != nil {
+		log.Panic(err)
+	}
+	return presets
+}
+
+// SetAllocationPresets sets the allocation presets of a financial advisor
+func SetAllocationPresets(presets AllocationPresets) {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	_, err := client.R().SetBody(presets).Post(base + "/api/iserver/account/allocation/presets")
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// AllocationAccount stores the values used to allocate orders to a single account.
+type AllocationAccount struct {
+	Name string `json:"name"`
+	Data []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"data"`
+}
+
+type allocationAccountsResponse struct {
+	Accounts []AllocationAccount `json:"accounts"`
+}
+
+// AllocationAccounts retrieves the accounts orders can be allocated to
+func AllocationAccounts() []AllocationAccount {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/iserver/account/allocation/accounts")
+	if err != nil {
+		log.Panic(err)
+	}
+	accounts := allocationAccountsResponse{}
+	err = json.Unmarshal(resp.Body(), &accounts)
+	if err != nil {
+		log.Panic(err)
+	}
+	return accounts.Accounts
+}
+
+// PlaceGroupOrders submits orders allocated across the accounts of an allocation group
+func (ba BrokerAccount) PlaceGroupOrders(group string, orders ...Order) []OrderReply {
+	grouped := make([]Order, len(orders))
+	for i, order := range orders {
+		order.AcctID = group
+		grouped[i] = order
+	}
+	return ba.placeOrders(grouped)
+}
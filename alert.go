@@ -0,0 +1,304 @@
+package ib
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	resty "github.com/go-resty/resty/v2"
+)
+
+// AlertConditionType represents what an alert condition is triggered by.
+type AlertConditionType int
+
+const (
+	// PriceCondition triggers on the price of a security.
+	PriceCondition AlertConditionType = 1
+	// TimeCondition triggers at a point in time.
+	TimeCondition AlertConditionType = 3
+	// MarginCondition triggers on the margin cushion of the account.
+	MarginCondition AlertConditionType = 4
+	// VolumeCondition triggers on the traded volume of a security.
+	VolumeCondition AlertConditionType = 6
+)
+
+// AlertOperator represents the comparison an alert condition makes.
+type AlertOperator string
+
+const (
+	// AtLeast triggers when the value is greater than or equal to the condition value.
+	AtLeast AlertOperator = ">="
+	// AtMost triggers when the value is less than or equal to the condition value.
+	AtMost AlertOperator = "<="
+)
+
+// Logic binds chaining alert conditions together.
+const (
+	logicAnd = "a"
+	logicOr  = "o"
+	logicEnd = "n"
+)
+
+// AlertCondition stores a single condition of an alert.
+type AlertCondition struct {
+	Type          AlertConditionType `json:"type"`
+	Conidex       string             `json:"conidex"`
+	Operator      AlertOperator      `json:"operator"`
+	TriggerMethod string             `json:"triggerMethod"`
+	Value         string             `json:"value"`
+	LogicBind     string             `json:"logicBind"`
+	TimeZone      string             `json:"timeZone,omitempty"`
+}
+
+// PriceAlert creates a condition triggered by the price of a security.
+func (s Security) PriceAlert(op AlertOperator, price float64) AlertCondition {
+	return AlertCondition{
+		Type:          PriceCondition,
+		Conidex:       strconv.Itoa(s.Conid),
+		Operator:      op,
+		TriggerMethod: "0",
+		Value:         strconv.FormatFloat(price, 'f', -1, 64),
+	}
+}
+
+// VolumeAlert creates a condition triggered by the traded volume of a security.
+func (s Security) VolumeAlert(op AlertOperator, volume int) AlertCondition {
+	return AlertCondition{
+		Type:          VolumeCondition,
+		Conidex:       strconv.Itoa(s.Conid),
+		Operator:      op,
+		TriggerMethod: "0",
+		Value:         strconv.Itoa(volume),
+	}
+}
+
+// TimeAlert creates a condition triggered at a point in time.
+// Times in the local or an unnamed time zone are sent in UTC, as the brokerage server only accepts named time zones.
+func TimeAlert(op AlertOperator, t time.Time) AlertCondition {
+	if t.Location() == time.Local || t.Location().String() == "" {
+		t = t.UTC()
+	}
+	return AlertCondition{
+		Type:          TimeCondition,
+		Conidex:       "*",
+		Operator:      op,
+		TriggerMethod: "0",
+		Value:         t.Format("20060102-15:04:05"),
+		TimeZone:      t.Location().String(),
+	}
+}
+
+// MarginAlert creates a condition triggered by the margin cushion of the account, in percent.
+func MarginAlert(op AlertOperator, cushion float64) AlertCondition {
+	return AlertCondition{
+		Type:          MarginCondition,
+		Conidex:       "*",
+		Operator:      op,
+		TriggerMethod: "0",
+		Value:         strconv.FormatFloat(cushion, 'f', -1, 64),
+	}
+}
+
+// Alert stores the parameters of a server side alert.
+type Alert struct {
+	OrderID         int              `json:"orderId"`
+	AlertName       string           `json:"alertName"`
+	AlertMessage    string           `json:"alertMessage"`
+	AlertRepeatable int              `json:"alertRepeatable"`
+	ExpireTime      string           `json:"expireTime,omitempty"`
+	OutsideRth      int              `json:"outsideRth"`
+	SendMessage     int              `json:"sendMessage"`
+	ShowPopup       int              `json:"showPopup"`
+	Email           string           `json:"email,omitempty"`
+	Tif             string           `json:"tif"`
+	Conditions      []AlertCondition `json:"conditions"`
+}
+
+// NewAlert creates an alert which is triggered when the condition is met.
+// The alert is good till cancelled and notifies via the mobile app.
+func NewAlert(name string, message string, condition AlertCondition) Alert {
+	condition.LogicBind = logicEnd
+	alert := Alert{
+		AlertName:    name,
+		AlertMessage: message,
+		SendMessage:  1,
+		Tif:          "GTC",
+		Conditions:   []AlertCondition{condition},
+	}
+	return alert
+}
+
+// chain appends a condition bound to the previous one by logic.
+func (a Alert) chain(logic string, condition AlertCondition) Alert {
+	conditions := make([]AlertCondition, len(a.Conditions), len(a.Conditions)+1)
+	copy(conditions, a.Conditions)
+	if len(conditions) > 0 {
+		conditions[len(conditions)-1].LogicBind = logic
+	}
+	condition.LogicBind = logicEnd
+	a.Conditions = append(conditions, condition)
+	return a
+}
+
+// And requires condition to be met as well as the previous condition.
+func (a Alert) And(condition AlertCondition) Alert {
+	return a.chain(logicAnd, condition)
+}
+
+// Or allows either condition or the previous condition to be met.
+func (a Alert) Or(condition AlertCondition) Alert {
+	return a.chain(logicOr, condition)
+}
+
+// Repeatable makes the alert trigger every time its conditions are met.
+func (a Alert) Repeatable() Alert {
+	a.AlertRepeatable = 1
+	return a
+}
+
+// Expires makes the alert expire at t instead of being good till cancelled.
+// The expiry time is sent in UTC.
+func (a Alert) Expires(t time.Time) Alert {
+	a.Tif = "GTD"
+	a.ExpireTime = t.UTC().Format("20060102-15:04:05")
+	return a
+}
+
+// AlertResponse stores the result of modifying an alert.
+type AlertResponse struct {
+	RequestID      int    `json:"request_id"`
+	OrderID        int    `json:"order_id"`
+	Success        bool   `json:"success"`
+	Text           string `json:"text"`
+	OrderStatus    string `json:"order_status"`
+	WarningMessage string `json:"warning_message"`
+}
+
+// CreateAlert creates an alert, or modifies it if its OrderID is set
+func (ba BrokerAccount) CreateAlert(alert Alert) AlertResponse {
+	ba.assertActive()
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().SetBody(alert).Post(base + "/api/iserver/account/" + ba.ID + "/alert")
+	if err != nil {
+		log.Panic(err)
+	}
+	ar := AlertResponse{}
+	err = json.Unmarshal(resp.Body(), &ar)
+	if err != nil {
+		log.Panic(err)
+	}
+	return ar
+}
+
+// AlertSummary stores the state of an alert in a list of alerts.
+type AlertSummary struct {
+	OrderID         int    `json:"order_id"`
+	Account         string `json:"account"`
+	AlertName       string `json:"alert_name"`
+	AlertActive     int    `json:"alert_active"`
+	OrderTime       string `json:"order_time"`
+	AlertTriggered  bool   `json:"alert_triggered"`
+	AlertRepeatable int    `json:"alert_repeatable"`
+}
+
+// Alerts retrieves all alerts of a brokerage account
+func (ba BrokerAccount) Alerts() []AlertSummary {
+	ba.assertActive()
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/iserver/account/" + ba.ID + "/alerts")
+	if err != nil {
+		log.Panic(err)
+	}
+	alerts := make([]AlertSummary, 0)
+	err = json.Unmarshal(resp.Body(), &alerts)
+	if err != nil {
+		log.Panic(err)
+	}
+	return alerts
+}
+
+// AlertDetails stores the details of an alert.
+type AlertDetails struct {
+	Account         string `json:"account"`
+	OrderID         int    `json:"order_id"`
+	AlertName       string `json:"alert_name"`
+	AlertMessage    string `json:"alert_message"`
+	AlertActive     int    `json:"alert_active"`
+	AlertRepeatable int    `json:"alert_repeatable"`
+	AlertTriggered  bool   `json:"alert_triggered"`
+	Tif             string `json:"tif"`
+	ExpireTime      string `json:"expire_time"`
+	OrderStatus     string `json:"order_status"`
+	Conditions      []struct {
+		ConditionType          AlertConditionType `json:"condition_type"`
+		Conidex                string             `json:"conidex"`
+		ContractDescription    string             `json:"contract_description_1"`
+		ConditionOperator      AlertOperator      `json:"condition_operator"`
+		ConditionTriggerMethod string             `json:"condition_trigger_method"`
+		ConditionValue         string             `json:"condition_value"`
+		ConditionLogicBind     bool               `json:"condition_logic_bind"`
+		ConditionTimeZone      string             `json:"condition_time_zone"`
+	} `json:"conditions"`
+}
+
+// Alert retrieves the details of an alert by its order id
+func (ba BrokerAccount) Alert(id int) AlertDetails {
+	ba.assertActive()
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/iserver/account/alert/" + strconv.Itoa(id) + "?type=Q")
+	if err != nil {
+		log.Panic(err)
+	}
+	details := AlertDetails{}
+	err = json.Unmarshal(resp.Body(), &details)
+	if err != nil {
+		log.Panic(err)
+	}
+	return details
+}
+
+// ActivateAlert activates or deactivates an alert
+func (ba BrokerAccount) ActivateAlert(id int, active bool) AlertResponse {
+	ba.assertActive()
+	body := map[string]int{
+		"alertId":     id,
+		"alertActive": 0,
+	}
+	if active {
+		body["alertActive"] = 1
+	}
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().SetBody(body).Post(base + "/api/iserver/account/" + ba.ID + "/alert/activate")
+	if err != nil {
+		log.Panic(err)
+	}
+	ar := AlertResponse{}
+	err = json.Unmarshal(resp.Body(), &ar)
+	if err != nil {
+		log.Panic(err)
+	}
+	return ar
+}
+
+// DeleteAlert deletes an alert
+func (ba BrokerAccount) DeleteAlert(id int) AlertResponse {
+	ba.assertActive()
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Delete(base + "/api/iserver/account/" + ba.ID + "/alert/" + strconv.Itoa(id))
+	if err != nil {
+		log.Panic(err)
+	}
+	ar := AlertResponse{}
+	err = json.Unmarshal(resp.Body(), &ar)
+	if err != nil {
+		log.Panic(err)
+	}
+	return ar
+}
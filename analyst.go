@@ -0,0 +1,379 @@
+package ib
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	resty "github.com/go-resty/resty/v2"
+	"github.com/rocketlaunchr/dataframe-go"
+)
+
+// PerformanceFrequency represents the frequency of performance data points.
+type PerformanceFrequency string
+
+const (
+	// Daily performance data.
+	Daily PerformanceFrequency = "D"
+	// Monthly performance data.
+	Monthly PerformanceFrequency = "M"
+	// Quarterly performance data.
+	Quarterly PerformanceFrequency = "Q"
+)
+
+// PerformancePoint stores a single value of a performance time series.
+type PerformancePoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// PerformanceSeries is a time series of performance data.
+type PerformanceSeries []PerformancePoint
+
+// ToDataFrame converts a performance series to a dataframe, naming the value column after name.
+func (ps PerformanceSeries) ToDataFrame(name string) *dataframe.DataFrame {
+	t := dataframe.NewSeriesTime("time", nil)
+	v := dataframe.NewSeriesFloat64(name, nil)
+	for _, p := range ps {
+		t.Append(p.Time)
+		v.Append(p.Value)
+	}
+	df := dataframe.NewDataFrame(t, v)
+	return df
+}
+
+// AccountPerformance stores the net asset value and time weighted returns of an account.
+type AccountPerformance struct {
+	AccountID         string
+	BaseCurrency      string
+	NAV               PerformanceSeries
+	CumulativeReturns PerformanceSeries
+	PeriodReturns     PerformanceSeries
+}
+
+// performanceData is a set of series for each account sharing the same dates.
+type performanceData struct {
+	Data []struct {
+		ID           string    `json:"id"`
+		IDType       string    `json:"idType"`
+		BaseCurrency string    `json:"baseCurrency"`
+		Start        string    `json:"start"`
+		End          string    `json:"end"`
+		Navs         []float64 `json:"navs"`
+		Returns      []float64 `json:"returns"`
+	} `json:"data"`
+	Freq  string   `json:"freq"`
+	Dates []string `json:"dates"`
+}
+
+type performanceResponse struct {
+	CurrencyType string          `json:"currencyType"`
+	RC           int             `json:"rc"`
+	ID           string          `json:"id"`
+	Included     []string        `json:"included"`
+	PM           string          `json:"pm"`
+	NAV          performanceData `json:"nav"`
+	CPS          performanceData `json:"cps"`
+	TPPS         performanceData `json:"tpps"`
+}
+
+// parsePerformanceDate parses the daily and monthly dates used by portfolio analyst.
+func parsePerformanceDate(date string) (time.Time, bool) {
+	for _, layout := range []string{"20060102", "200601"} {
+		t, err := time.Parse(layout, date)
+		if err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// series zips the dates of the data set with a set of values.
+func (pd performanceData) series(values []float64) PerformanceSeries {
+	ps := make(PerformanceSeries, 0)
+	for i, v := range values {
+		if i >= len(pd.Dates) {
+			break
+		}
+		t, ok := parsePerformanceDate(pd.Dates[i])
+		if !ok {
+			continue
+		}
+		ps = append(ps, PerformancePoint{Time: t, Value: v})
+	}
+	return ps
+}
+
+// Performance retrieves the net asset value and returns of accounts from portfolio analyst, keyed by account id.
+func Performance(freq PerformanceFrequency, accountIDs ...string) map[string]AccountPerformance {
+	body := map[string]interface{}{
+		"acctIds": accountIDs,
+		"freq":    string(freq),
+	}
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().SetBody(body).Post(base + "/api/pa/performance")
+	if err != nil {
+		log.Panic(err)
+	}
+	pr := performanceResponse{}
+	err = json.Unmarshal(resp.Body(), &pr)
+	if err != nil {
+		log.Panic(err)
+	}
+	performance := make(map[string]AccountPerformance)
+	get := func(id string, currency string) AccountPerformance {
+		ap, ok := performance[id]
+		if !ok {
+			ap = AccountPerformance{AccountID: id}
+		}
+		if currency != "" {
+			ap.BaseCurrency = currency
+		}
+		return ap
+	}
+	for _, d := range pr.NAV.Data {
+		ap := get(d.ID, d.BaseCurrency)
+		ap.NAV = pr.NAV.series(d.Navs)
+		performance[d.ID] = ap
+	}
+	for _, d := range pr.CPS.Data {
+		ap := get(d.ID, d.BaseCurrency)
+		ap.CumulativeReturns = pr.CPS.series(d.Returns)
+		performance[d.ID] = ap
+	}
+	for _, d := range pr.TPPS.Data {
+		ap := get(d.ID, d.BaseCurrency)
+		ap.PeriodReturns = pr.TPPS.series(d.Returns)
+		performance[d.ID] = ap
+	}
+	return performance
+}
+
+// analystNumber decodes numbers portfolio analyst sends either as JSON numbers or as strings.
+type analystNumber float64
+
+func (n *analystNumber) UnmarshalJSON(b []byte) error {
+	text := strings.Trim(string(b), `"`)
+	if text == "" || text == "null" {
+		*n = 0
+		return nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return err
+	}
+	*n = analystNumber(f)
+	return nil
+}
+
+// AccountBalanceSummary stores the change in value of an account over the summary period.
+type AccountBalanceSummary struct {
+	ID           string
+	IDType       string
+	BaseCurrency string
+	Start        time.Time
+	End          time.Time
+	StartValue   float64
+	EndValue     float64
+	Change       float64
+	Return       float64
+}
+
+// AnalystSummary stores the balance summary of accounts from portfolio analyst.
+type AnalystSummary struct {
+	CurrencyType string
+	ID           string
+	Included     []string
+	PM           string
+	Accounts     []AccountBalanceSummary
+}
+
+type analystSummaryResponse struct {
+	CurrencyType string   `json:"currencyType"`
+	RC           int      `json:"rc"`
+	ID           string   `json:"id"`
+	Included     []string `json:"included"`
+	PM           string   `json:"pm"`
+	Data         []struct {
+		ID           string        `json:"id"`
+		IDType       string        `json:"idType"`
+		BaseCurrency string        `json:"baseCurrency"`
+		Start        string        `json:"start"`
+		End          string        `json:"end"`
+		StartVal     analystNumber `json:"startVal"`
+		EndVal       analystNumber `json:"endVal"`
+		Chg          analystNumber `json:"chg"`
+		Rtn          analystNumber `json:"rtn"`
+	} `json:"data"`
+}
+
+// PerformanceSummary retrieves the balance summary of accounts from portfolio analyst.
+func PerformanceSummary(accountIDs ...string) AnalystSummary {
+	body := map[string]interface{}{
+		"acctIds": accountIDs,
+	}
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().SetBody(body).Post(base + "/api/pa/summary")
+	if err != nil {
+		log.Panic(err)
+	}
+	sr := analystSummaryResponse{}
+	err = json.Unmarshal(resp.Body(), &sr)
+	if err != nil {
+		log.Panic(err)
+	}
+	summary := AnalystSummary{
+		CurrencyType: sr.CurrencyType,
+		ID:           sr.ID,
+		Included:     sr.Included,
+		PM:           sr.PM,
+		Accounts:     make([]AccountBalanceSummary, 0, len(sr.Data)),
+	}
+	for _, d := range sr.Data {
+		start, _ := parsePerformanceDate(d.Start)
+		end, _ := parsePerformanceDate(d.End)
+		summary.Accounts = append(summary.Accounts, AccountBalanceSummary{
+			ID:           d.ID,
+			IDType:       d.IDType,
+			BaseCurrency: d.BaseCurrency,
+			Start:        start,
+			End:          end,
+			StartValue:   float64(d.StartVal),
+			EndValue:     float64(d.EndVal),
+			Change:       float64(d.Chg),
+			Return:       float64(d.Rtn),
+		})
+	}
+	return summary
+}
+
+// Transaction stores a single transaction from portfolio analyst.
+type Transaction struct {
+	AcctID   string  `json:"acctid"`
+	Conid    int     `json:"conid"`
+	Date     string  `json:"date"`
+	Type     string  `json:"type"`
+	Desc     string  `json:"desc"`
+	Currency string  `json:"cur"`
+	FXRate   float64 `json:"fxRate"`
+	Price    float64 `json:"pr"`
+	Quantity float64 `json:"qty"`
+	Amount   float64 `json:"amt"`
+}
+
+// transactionZones maps the time zone abbreviations used in transaction dates to their location,
+// as Go only knows the offset of abbreviations used by the local time zone.
+var transactionZones = map[string]string{
+	"EST":  "America/New_York",
+	"EDT":  "America/New_York",
+	"CST":  "America/Chicago",
+	"CDT":  "America/Chicago",
+	"GMT":  "UTC",
+	"UTC":  "UTC",
+	"BST":  "Europe/London",
+	"CET":  "Europe/Berlin",
+	"CEST": "Europe/Berlin",
+	"HKT":  "Asia/Hong_Kong",
+	"JST":  "Asia/Tokyo",
+	"AEST": "Australia/Sydney",
+	"AEDT": "Australia/Sydney",
+}
+
+// Time parses the date of a transaction, e.g. "Mon Oct 05 00:00:00 EDT 2020".
+func (t Transaction) Time() (time.Time, error) {
+	fields := strings.Fields(t.Date)
+	if len(fields) != 6 {
+		return time.Time{}, fmt.Errorf("ib: unknown transaction date format %q", t.Date)
+	}
+	zone, ok := transactionZones[fields[4]]
+	if !ok {
+		return time.Time{}, fmt.Errorf("ib: unknown time zone %s in transaction date %q", fields[4], t.Date)
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	date := strings.Join(append(fields[:4:4], fields[5]), " ")
+	return time.ParseInLocation("Mon Jan 02 15:04:05 2006", date, loc)
+}
+
+// Transactions is an array of transactions
+type Transactions []Transaction
+
+type transactionsResponse struct {
+	RC               int          `json:"rc"`
+	ID               string       `json:"id"`
+	Currency         string       `json:"currency"`
+	From             int64        `json:"from"`
+	To               int64        `json:"to"`
+	IncludesRealTime bool         `json:"includesRealTime"`
+	Transactions     Transactions `json:"transactions"`
+}
+
+// TransactionHistory retrieves the transactions of securities over the last number of days from portfolio analyst.
+// Amounts are converted to currency.
+func TransactionHistory(accountIDs []string, conids []int, currency string, days int) Transactions {
+	body := map[string]interface{}{
+		"acctIds":  accountIDs,
+		"conids":   conids,
+		"currency": currency,
+		"days":     days,
+	}
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().SetBody(body).Post(base + "/api/pa/transactions")
+	if err != nil {
+		log.Panic(err)
+	}
+	tr := transactionsResponse{}
+	err = json.Unmarshal(resp.Body(), &tr)
+	if err != nil {
+		log.Panic(err)
+	}
+	return tr.Transactions
+}
+
+// ByConid groups transactions by contract id.
+func (t Transactions) ByConid() map[int]Transactions {
+	grouped := make(map[int]Transactions)
+	for _, tx := range t {
+		grouped[tx.Conid] = append(grouped[tx.Conid], tx)
+	}
+	return grouped
+}
+
+// ToDataFrame converts transactions to a dataframe.
+// The time of transactions with a date which can't be parsed is left empty.
+func (t Transactions) ToDataFrame() *dataframe.DataFrame {
+	tm := dataframe.NewSeriesTime("time", nil)
+	acct := dataframe.NewSeriesString("account", nil)
+	conid := dataframe.NewSeriesInt64("conid", nil)
+	typ := dataframe.NewSeriesString("type", nil)
+	cur := dataframe.NewSeriesString("currency", nil)
+	pr := dataframe.NewSeriesFloat64("price", nil)
+	qty := dataframe.NewSeriesFloat64("quantity", nil)
+	amt := dataframe.NewSeriesFloat64("amount", nil)
+	for _, tx := range t {
+		when, err := tx.Time()
+		if err != nil {
+			tm.Append(nil)
+		} else {
+			tm.Append(when)
+		}
+		acct.Append(tx.AcctID)
+		conid.Append(tx.Conid)
+		typ.Append(tx.Type)
+		cur.Append(tx.Currency)
+		pr.Append(tx.Price)
+		qty.Append(tx.Quantity)
+		amt.Append(tx.Amount)
+	}
+	df := dataframe.NewDataFrame(tm, acct, conid, typ, cur, pr, qty, amt)
+	return df
+}
@@ -3,7 +3,9 @@ package ib
 import (
 	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"log"
+	"sync"
 
 	resty "github.com/go-resty/resty/v2"
 )
@@ -47,18 +49,30 @@ type BrokerAccount struct {
 
 // Brokers retrieves all of an accounts brokerage accounts
 func Brokers() BrokerAccounts {
+	brokerAccounts, err := brokers()
+	if err != nil {
+		log.Panic(err)
+	}
+	return brokerAccounts
+}
+
+func brokers() (BrokerAccounts, error) {
 	client := resty.New()
 	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
 	resp, err := client.R().Get(base + "/api/iserver/accounts")
 	if err != nil {
-		log.Panic(err)
+		return BrokerAccounts{}, err
+	}
+	if resp.IsError() {
+		return BrokerAccounts{}, fmt.Errorf("ib: brokerage accounts: %s", resp.Status())
 	}
 	brokerAccounts := BrokerAccounts{}
 	err = json.Unmarshal(resp.Body(), &brokerAccounts)
 	if err != nil {
-		log.Panic(err)
+		return BrokerAccounts{}, err
 	}
-	return brokerAccounts
+	setActiveAccount(brokerAccounts.SelectedAccount)
+	return brokerAccounts, nil
 }
 
 // Selected returns the default/active brokerage account
@@ -68,3 +82,87 @@ func (ba BrokerAccounts) Selected() BrokerAccount {
 	}
 	return brokerAccount
 }
+
+// activeAccount is the last known account the iserver endpoints act on.
+var (
+	activeMu      sync.Mutex
+	activeAccount string
+)
+
+func setActiveAccount(id string) {
+	activeMu.Lock()
+	activeAccount = id
+	activeMu.Unlock()
+}
+
+func getActiveAccount() string {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	return activeAccount
+}
+
+type selectAccountResponse struct {
+	Set    bool   `json:"set"`
+	AcctID string `json:"acctId"`
+}
+
+// Select switches the active brokerage account.
+// With multi-account and advisor logins the iserver endpoints act on the active account.
+func (ba BrokerAccounts) Select(id string) BrokerAccount {
+	body := map[string]string{
+		"acctId": id,
+	}
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().SetBody(body).Post(base + "/api/iserver/account")
+	if err != nil {
+		log.Panic(err)
+	}
+	sr := selectAccountResponse{}
+	err = json.Unmarshal(resp.Body(), &sr)
+	if err != nil {
+		log.Panic(err)
+	}
+	if !sr.Set {
+		log.Panic("ib: failed to select account " + id)
+	}
+	// Verify the switch, the server doesn't always report
+	// a failed switch in its response.
+	if selected := Brokers().SelectedAccount; selected != id {
+		log.Panic("ib: failed to select account " + id + ", active account is " + selected)
+	}
+	return BrokerAccount{
+		ID: id,
+	}
+}
+
+// checkActive returns an error if the brokerage account isn't the active account,
+// as account scoped iserver endpoints such as orders and alerts would otherwise silently act on another account.
+// Accounts without an id are not checked.
+// The active account is cached from the last call to Brokers or Select, so a switch made outside this process,
+// e.g. by another client or after re-authenticating, goes unnoticed until Brokers is called again.
+func (ba BrokerAccount) checkActive() error {
+	if ba.ID == "" {
+		return nil
+	}
+	active := getActiveAccount()
+	if active == "" {
+		brokerAccounts, err := brokers()
+		if err != nil {
+			return err
+		}
+		active = brokerAccounts.SelectedAccount
+	}
+	if active != ba.ID {
+		return fmt.Errorf("ib: account %s is not the active account %s, switch with BrokerAccounts.Select", ba.ID, active)
+	}
+	return nil
+}
+
+// assertActive panics if the brokerage account isn't the active account, see checkActive.
+func (ba BrokerAccount) assertActive() {
+	err := ba.checkActive()
+	if err != nil {
+		log.Panic(err)
+	}
+}
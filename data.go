@@ -6,6 +6,7 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"time"
 
 	resty "github.com/go-resty/resty/v2"
 	"github.com/rocketlaunchr/dataframe-go"
@@ -21,14 +22,33 @@ type HistoricalDataFrame struct {
 	T int64   `json:"t"`
 }
 
+// DataFrameOption changes the columns of a converted dataframe.
+type DataFrameOption func(*dataFrameOptions)
+
+type dataFrameOptions struct {
+	timeLocation *time.Location
+}
+
+// WithTimeColumn adds a "datetime" column holding the time of each frame in loc.
+func WithTimeColumn(loc *time.Location) DataFrameOption {
+	return func(o *dataFrameOptions) {
+		o.timeLocation = loc
+	}
+}
+
 // ToDataFrame converts historical market data to dataframes for technical analysis.
-func (hd Historical) ToDataFrame() *dataframe.DataFrame {
+func (hd Historical) ToDataFrame(opts ...DataFrameOption) *dataframe.DataFrame {
+	options := dataFrameOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
 	o := dataframe.NewSeriesFloat64("open", nil)
 	c := dataframe.NewSeriesFloat64("close", nil)
 	h := dataframe.NewSeriesFloat64("high", nil)
 	l := dataframe.NewSeriesFloat64("low", nil)
 	v := dataframe.NewSeriesInt64("volume", nil)
 	t := dataframe.NewSeriesInt64("time", nil)
+	dt := dataframe.NewSeriesTime("datetime", nil)
 	for _, f := range hd.Data {
 		o.Append(f.O)
 		c.Append(f.C)
@@ -36,19 +56,115 @@ func (hd Historical) ToDataFrame() *dataframe.DataFrame {
 		l.Append(f.L)
 		v.Append(f.V)
 		t.Append(f.T)
+		if options.timeLocation != nil {
+			dt.Append(f.Time().In(options.timeLocation))
+		}
+	}
+	if options.timeLocation != nil {
+		return dataframe.NewDataFrame(t, dt, o, c, h, l, v)
 	}
 	df := dataframe.NewDataFrame(t, o, c, h, l, v)
 	return df
 }
 
+// Time returns the time of the frame.
+func (f HistoricalDataFrame) Time() time.Time {
+	return time.Unix(0, f.T*int64(time.Millisecond))
+}
+
+// Bar stores a single bar of historical market data with prices and volume corrected by the price and volume factors.
+type Bar struct {
+	Time   time.Time
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume float64
+}
+
+// Bars returns the historical market data as bars with times in loc, or UTC if loc is nil.
+// Prices are divided by the price factor and volumes multiplied by the volume factor.
+func (hd Historical) Bars(loc *time.Location) []Bar {
+	if loc == nil {
+		loc = time.UTC
+	}
+	priceFactor := float64(hd.PriceFactor)
+	if priceFactor <= 0 {
+		priceFactor = 1
+	}
+	volumeFactor := float64(hd.VolumeFactor)
+	if volumeFactor <= 0 {
+		volumeFactor = 1
+	}
+	bars := make([]Bar, 0, len(hd.Data))
+	for _, f := range hd.Data {
+		bars = append(bars, Bar{
+			Time:   f.Time().In(loc),
+			Open:   f.O / priceFactor,
+			High:   f.H / priceFactor,
+			Low:    f.L / priceFactor,
+			Close:  f.C / priceFactor,
+			Volume: float64(f.V) * volumeFactor,
+		})
+	}
+	return bars
+}
+
+// Extreme stores the high or low of historical market data with its price and volume corrected by the price and volume factors.
+type Extreme struct {
+	Price  float64
+	Volume float64
+	// Minutes is the number of minutes after StartTime the extreme was reached.
+	Minutes int
+}
+
+// parseExtreme parses a high or low in the price/volume/minutes format of the brokerage server.
+func (hd Historical) parseExtreme(s string) (Extreme, bool) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 3 {
+		return Extreme{}, false
+	}
+	price, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return Extreme{}, false
+	}
+	volume, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return Extreme{}, false
+	}
+	minutes, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return Extreme{}, false
+	}
+	if hd.PriceFactor > 0 {
+		price /= float64(hd.PriceFactor)
+	}
+	if hd.VolumeFactor > 0 {
+		volume *= float64(hd.VolumeFactor)
+	}
+	return Extreme{Price: price, Volume: volume, Minutes: minutes}, true
+}
+
+// Highest returns the parsed High of the historical market data.
+// It reports false if High is missing or malformed, e.g. for merged downloads.
+func (hd Historical) Highest() (Extreme, bool) {
+	return hd.parseExtreme(hd.High)
+}
+
+// Lowest returns the parsed Low of the historical market data.
+// It reports false if Low is missing or malformed, e.g. for merged downloads.
+func (hd Historical) Lowest() (Extreme, bool) {
+	return hd.parseExtreme(hd.Low)
+}
+
 // Historical stores historical market data returned from the brokerage server.
 type Historical struct {
 	Symbol            string                `json:"symbol"`
 	Text              string                `json:"text"`
 	PriceFactor       int                   `json:"priceFactor"`
 	StartTime         string                `json:"startTime"`
-	High              string                `json:"high"`
-	Low               string                `json:"low"`
+	High              string                `json:"high"` // price/volume/minutes, see Highest
+	Low               string                `json:"low"`  // price/volume/minutes, see Lowest
 	TimePeriod        string                `json:"timePeriod"`
 	BarLength         int                   `json:"barLength"`
 	MdAvailability    string                `json:"mdAvailability"`
@@ -82,158 +198,267 @@ const (
 	Year TimeUnit = "y"
 )
 
-// Historical retrieves historical market data for a security.
+// Historical retrieves historical market data for a security, reading through HistoricalStore if set.
+// It panics if the period or bar size is out of the range the brokerage server accepts.
 func (s Security) Historical(period int, unit TimeUnit, barSize int, barUnit TimeUnit) Historical {
-	client := resty.New()
-	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
-	resp, err := client.R().Get(base + "/api/iserver/marketdata/history?conid=" + strconv.Itoa(s.Conid) + "&period=" + strconv.Itoa(period) + string(unit) + "&bar=" + strconv.Itoa(barSize) + string(barUnit))
-	if err != nil {
-		log.Panic(err)
+	if HistoricalStore != nil {
+		historical, err := HistoricalStore.Update(s, period, unit, barSize, barUnit)
+		if err != nil {
+			log.Panic(err)
+		}
+		return historical
 	}
-	historical := Historical{}
-	err = json.Unmarshal(resp.Body(), &historical)
+	historical, err := s.History(period, unit, barSize, barUnit).Fetch()
 	if err != nil {
 		log.Panic(err)
 	}
 	return historical
 }
 
-type Snapshot struct {
-	LastPrice                      float64 `json:"31,string,omitempty"`
-	Symbol                         string  `json:"55,omitempty"`
-	Text                           string  `json:"58,omitempty"`
-	High                           float64 `json:"70,string,omitempty"`
-	Low                            float64 `json:"71,string,omitempty"`
-	Position                       float64 `json:"72,omitempty"`
-	MarketValue                    string  `json:"73,omitempty"`
-	AveragePrice                   float64 `json:"74,string,omitempty"`
-	UnrealizedPnL                  float64 `json:"75,omitempty"`
-	FormattedPosition              string  `json:"76,omitempty"`
-	FormattedUnrealizedPnL         string  `json:"77,omitempty"`
-	DailyPnL                       float64 `json:"78_raw,omitempty"`
-	ChangePrice                    float64 `json:"82,string,omitempty"`
-	ChangePercent                  float64 `json:"83,omitempty"`
-	BidPrice                       float64 `json:"84,string,omitempty"`
-	AskSize                        int     `json:"85,string,omitempty"`
-	AskPrice                       float64 `json:"86,string,omitempty"`
-	Volume                         float64 `json:"87_raw,omitempty"`
-	BidSize                        int     `json:"88,string,omitempty"`
-	SecurityType                   string  `json:"6070,omitempty"`
-	MarketDataDeliveryMethodMarker string  `json:"6119,omitempty"`
-	UnderlyingConid                int     `json:"6457,string,omitempty"`
-	MarketDataAvailability         string  `json:"6509,omitempty"`
-	CompanyName                    string  `json:"7051,omitempty"`
-	LastSize                       int     `json:"7059,string,omitempty"`
-	ContractDescription            string  `json:"7219,omitempty"`
-	ListingExchange                string  `json:"7221,omitempty"`
-	Industry                       string  `json:"7280,omitempty"`
-	Category                       string  `json:"7281,omitempty"`
-	AverageDailyVolume             string  `json:"7282,omitempty"`
-	HistoricVolume30D              string  `json:"7284,omitempty"`
-	DividendAmount                 float64 `json:"7286,string,omitempty"`
-	DividendYieldPercentage        string  `json:"7287,omitempty"`
-	DividendExDate                 string  `json:"7288,omitempty"`
-	MarketCap                      string  `json:"7289,omitempty"`
-	PE                             float64 `json:"7290,string,omitempty"`
-	EPS                            float64 `json:"7291,string,omitempty"`
-	CostBasis                      float64 `json:"7292_raw,omitempty"`
-	WeekHigh52                     float64 `json:"7293,string,omitempty"`
-	WeekLow52                      float64 `json:"7294,string,omitempty"`
-	OpenPrice                      float64 `json:"7295,string,omitempty"`
-	Conid                          int     `json:"conid"`
-	ServerID                       string  `json:"server_id,omitempty"`
-	Updated                        int64   `json:"_updated,omitempty"`
-}
-
 type Snapshots []Snapshot
 
 // MarketDataField represents a field to request market data for.
 // IB decided it was a fun idea to assign each field a number instead of a name.
-// So now I must resort to this horrific mess
+// The fields, the Snapshot struct and its decoder are generated from the table in fields.csv,
+// add a row there and run go generate to add a field.
 type MarketDataField string
 
+//go:generate go run gen_fields.go
+
+// String returns the name of a market data field.
+func (f MarketDataField) String() string {
+	if name, ok := fieldNames[f]; ok {
+		return name
+	}
+	if strings.HasSuffix(string(f), "_raw") {
+		if name, ok := fieldNames[MarketDataField(strings.TrimSuffix(string(f), "_raw"))]; ok {
+			return name + "Raw"
+		}
+	}
+	return string(f)
+}
+
+// SnapshotTimeout is how long snapshots poll for requested fields before giving up.
+var SnapshotTimeout = 5 * time.Second
+
 const (
-	LastPrice                      MarketDataField = "31"
-	Symbol                         MarketDataField = "55"
-	Text                           MarketDataField = "58"
-	High                           MarketDataField = "70"
-	Low                            MarketDataField = "71"
-	Pos                            MarketDataField = "72"
-	MarketValue                    MarketDataField = "73"
-	AveragePrice                   MarketDataField = "74"
-	UnrealizedPnL                  MarketDataField = "75"
-	FormattedPosition              MarketDataField = "76"
-	FormattedUnrealizedPnL         MarketDataField = "77"
-	DailyPnL                       MarketDataField = "78"
-	ChangePrice                    MarketDataField = "82"
-	ChangePercent                  MarketDataField = "83"
-	BidPrice                       MarketDataField = "84"
-	AskSize                        MarketDataField = "85"
-	AskPrice                       MarketDataField = "86"
-	Volume                         MarketDataField = "87"
-	BidSize                        MarketDataField = "88"
-	Exchange                       MarketDataField = "6004"
-	Conid                          MarketDataField = "6008"
-	SecurityType                   MarketDataField = "6070"
-	Months                         MarketDataField = "6072"
-	RegularExpiry                  MarketDataField = "6073"
-	MarketDataDeliveryMethodMarker MarketDataField = "6119"
-	UnderlyingConid                MarketDataField = "6457"
-	MarketDataAvailability         MarketDataField = "6509"
-	CompanyName                    MarketDataField = "7051"
-	LastSize                       MarketDataField = "7059"
-	ConidExchange                  MarketDataField = "7094"
-	ContractDescription            MarketDataField = "7219"
-	ContractDescriptionAlt         MarketDataField = "7220"
-	ListingExchange                MarketDataField = "7221"
-	Industry                       MarketDataField = "7280"
-	Category                       MarketDataField = "7281"
-	AverageDailyVolume             MarketDataField = "7282"
-	HistoricVolume30D              MarketDataField = "7284"
-	PutCallRatio                   MarketDataField = "7285"
-	DividendAmount                 MarketDataField = "7286"
-	DividendYieldPercentage        MarketDataField = "7287"
-	DividendExDate                 MarketDataField = "7288"
-	MarketCap                      MarketDataField = "7289"
-	PE                             MarketDataField = "7290"
-	EPS                            MarketDataField = "7291"
-	CostBasis                      MarketDataField = "7292"
-	WeekHigh52                     MarketDataField = "7293"
-	WeekLow52                      MarketDataField = "7294"
-	OpenPrice                      MarketDataField = "7295"
-	ClosePrice                     MarketDataField = "7296"
-	Delta                          MarketDataField = "7308"
-	Gamma                          MarketDataField = "7309"
-	Theta                          MarketDataField = "7310"
-	Vega                           MarketDataField = "7311"
-	ImpliedVolatilityOption        MarketDataField = "7633"
+	snapshotInitialBackoff = 100 * time.Millisecond
+	snapshotMaxBackoff     = time.Second
 )
 
-// Snapshot retrieves a market data snapshot by fields
-func (s Security) Snapshot(fields ...MarketDataField) Snapshots {
-	fieldStrings := make([]string, 0)
+// MissingFields stores the requested fields which never arrived, keyed by contract id.
+type MissingFields map[int][]MarketDataField
+
+// rawSnapshot stores the fields of a snapshot as returned from the brokerage server.
+type rawSnapshot map[string]json.RawMessage
+
+// missing returns the fields which are not present in the snapshot.
+func (rs rawSnapshot) missing(fields []MarketDataField) []MarketDataField {
+	missing := make([]MarketDataField, 0)
 	for _, f := range fields {
-		fieldStrings = append(fieldStrings, string(f))
+		if _, ok := rs[string(f)]; !ok {
+			missing = append(missing, f)
+		}
+	}
+	return missing
+}
+
+// requestSnapshots requests a single snapshot of a set of contract ids.
+func requestSnapshots(conids []int, builtFields string) []rawSnapshot {
+	conidStrings := make([]string, 0)
+	for _, c := range conids {
+		conidStrings = append(conidStrings, strconv.Itoa(c))
 	}
-	builtFields := strings.Join(fieldStrings, ",")
 	client := resty.New()
 	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
-	resp, err := client.R().Get(base + "/api/iserver/marketdata/snapshot?conids=" + strconv.Itoa(s.Conid) + "&fields=" + builtFields)
+	resp, err := client.R().Get(base + "/api/iserver/marketdata/snapshot?conids=" + strings.Join(conidStrings, ",") + "&fields=" + builtFields)
 	if err != nil {
 		log.Panic(err)
 	}
-	// Rerequest the snapshot
-	// IBKR seems to need an initial request to initiate the market
-	// data transaction and rerequesting the snapshot will
-	// give us the desired data.
-	resp, err = client.R().Get(base + "/api/iserver/marketdata/snapshot?conids=" + strconv.Itoa(s.Conid) + "&fields=" + builtFields)
+	raw := make([]rawSnapshot, 0)
+	err = json.Unmarshal(resp.Body(), &raw)
 	if err != nil {
 		log.Panic(err)
 	}
-	snapshots := Snapshots{}
-	err = json.Unmarshal(resp.Body(), &snapshots)
+	return raw
+}
+
+// pollSnapshots requests snapshots of a set of contract ids until every field is present or the timeout expires.
+// IBKR needs an initial request to open the market data line of a contract, so lines which
+// were not open yet are rerequested straight away, then polling backs off.
+func pollSnapshots(conids []int, fields []MarketDataField, timeout time.Duration) (map[int]rawSnapshot, MissingFields) {
+	if RequireRealTime {
+		fields = append(fields[:len(fields):len(fields)], MarketDataAvailability)
+	}
+	fieldStrings := make([]string, 0)
+	for _, f := range fields {
+		fieldStrings = append(fieldStrings, string(f))
+	}
+	builtFields := strings.Join(fieldStrings, ",")
+	warmingUp := false
+	for _, c := range conids {
+		if !Subscriptions.Touch(c) {
+			warmingUp = true
+		}
+	}
+	merged := make(map[int]rawSnapshot)
+	for _, c := range conids {
+		merged[c] = rawSnapshot{}
+	}
+	deadline := time.Now().Add(timeout)
+	backoff := snapshotInitialBackoff
+	pending := conids
+	for {
+		for _, raw := range requestSnapshots(pending, builtFields) {
+			var conid int
+			err := json.Unmarshal(raw["conid"], &conid)
+			if err != nil {
+				continue
+			}
+			rs, ok := merged[conid]
+			if !ok {
+				continue
+			}
+			for k, v := range raw {
+				rs[k] = v
+			}
+		}
+		incomplete := make([]int, 0)
+		for _, c := range pending {
+			if len(merged[c].missing(fields)) > 0 {
+				incomplete = append(incomplete, c)
+			}
+		}
+		pending = incomplete
+		if len(pending) == 0 || time.Now().After(deadline) {
+			break
+		}
+		if warmingUp {
+			warmingUp = false
+			continue
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > snapshotMaxBackoff {
+			backoff = snapshotMaxBackoff
+		}
+	}
+	missing := MissingFields{}
+	for _, c := range pending {
+		missing[c] = merged[c].missing(fields)
+	}
+	return merged, missing
+}
+
+// decode converts a raw snapshot to a snapshot.
+func (rs rawSnapshot) decode() Snapshot {
+	snapshot := Snapshot{
+		Values: Values{},
+	}
+	for k, v := range rs {
+		snapshot.Values[MarketDataField(k)] = newValue(v)
+	}
+	snapshot.decodeFields()
+	last := snapshot.Values[LastPrice].Parse()
+	snapshot.PreviousClose = last.PreviousClose
+	snapshot.Halted = last.Halted
+	snapshot.Delayed = snapshot.Status().IsDelayed()
+	return snapshot
+}
+
+// Snapshot retrieves a market data snapshot by fields.
+// Fields which don't arrive within SnapshotTimeout are left empty.
+func (s Security) Snapshot(fields ...MarketDataField) Snapshots {
+	snapshots, _, err := s.SnapshotWait(SnapshotTimeout, fields...)
 	if err != nil {
 		log.Panic(err)
 	}
 	return snapshots
 }
+
+// SnapshotWait retrieves a market data snapshot by fields, polling until every field is present or the timeout expires.
+// Fields which never arrived are reported as missing.
+// An error is returned if RequireRealTime is set and the market data isn't real time.
+func (s Security) SnapshotWait(timeout time.Duration, fields ...MarketDataField) (Snapshots, MissingFields, error) {
+	merged, missing := pollSnapshots([]int{s.Conid}, fields, timeout)
+	snapshot := merged[s.Conid].decode()
+	err := checkRealTime(snapshot.Conid, snapshot.Status())
+	if err != nil {
+		return nil, missing, err
+	}
+	return Snapshots{snapshot}, missing, nil
+}
+
+// snapshotMaxConids is the number of contract ids the snapshot endpoint accepts per request.
+const snapshotMaxConids = 100
+
+// BatchSnapshot retrieves market data snapshots of many securities by fields.
+// Fields which don't arrive within SnapshotTimeout are left empty.
+func BatchSnapshot(securities []Security, fields ...MarketDataField) Snapshots {
+	snapshots, _, err := BatchSnapshotWait(SnapshotTimeout, securities, fields...)
+	if err != nil {
+		log.Panic(err)
+	}
+	return snapshots
+}
+
+// BatchSnapshotWait retrieves market data snapshots of many securities by fields,
+// requesting up to 100 securities at a time and polling each batch until every field is present or the timeout expires.
+// Batches are no larger than the line cap of Subscriptions, so a batch never evicts its own lines.
+// The timeout applies to each batch rather than the whole call.
+// Snapshots are returned in the order of securities, duplicates are only requested once.
+// An error is returned if RequireRealTime is set and the market data of any security isn't real time.
+func BatchSnapshotWait(timeout time.Duration, securities []Security, fields ...MarketDataField) (Snapshots, MissingFields, error) {
+	conids := make([]int, 0)
+	seen := make(map[int]bool)
+	for _, s := range securities {
+		if !seen[s.Conid] {
+			seen[s.Conid] = true
+			conids = append(conids, s.Conid)
+		}
+	}
+	snapshots := Snapshots{}
+	missing := MissingFields{}
+	size := snapshotMaxConids
+	if lines := Subscriptions.MaxLines(); lines > 0 && lines < size {
+		size = lines
+	}
+	for start := 0; start < len(conids); start += size {
+		end := start + size
+		if end > len(conids) {
+			end = len(conids)
+		}
+		chunk := conids[start:end]
+		merged, chunkMissing := pollSnapshots(chunk, fields, timeout)
+		for c, m := range chunkMissing {
+			missing[c] = m
+		}
+		for _, c := range chunk {
+			snapshot := merged[c].decode()
+			err := checkRealTime(c, snapshot.Status())
+			if err != nil {
+				return nil, missing, err
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	return snapshots, missing, nil
+}
+
+// Snapshot retrieves market data snapshots of all positions by fields
+func (p Positions) Snapshot(ba BrokerAccount, fields ...MarketDataField) Snapshots {
+	securities := make([]Security, 0)
+	for _, pos := range p {
+		securities = append(securities, ba.Security(pos))
+	}
+	return BatchSnapshot(securities, fields...)
+}
+
+// ByConid returns the snapshots keyed by contract id.
+func (s Snapshots) ByConid() map[int]Snapshot {
+	snapshots := make(map[int]Snapshot)
+	for _, snapshot := range s {
+		snapshots[snapshot.Conid] = snapshot
+	}
+	return snapshots
+}
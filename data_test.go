@@ -0,0 +1,36 @@
+package ib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistoricalBars(t *testing.T) {
+	hd := Historical{
+		PriceFactor:  100,
+		VolumeFactor: 100,
+		Data:         []HistoricalDataFrame{{O: 11500, H: 11800, L: 11400, C: 11700, V: 25, T: 1601904600000}},
+	}
+	bars := hd.Bars(nil)
+	want := Bar{Time: time.Date(2020, 10, 5, 13, 30, 0, 0, time.UTC), Open: 115, High: 118, Low: 114, Close: 117, Volume: 2500}
+	if len(bars) != 1 || bars[0] != want {
+		t.Errorf("Bars(nil) = %+v, want %+v", bars, want)
+	}
+}
+
+func TestHistoricalHighestAndLowest(t *testing.T) {
+	hd := Historical{PriceFactor: 100, VolumeFactor: 100, High: "11800/35/90", Low: "11400/12/5"}
+	high, ok := hd.Highest()
+	if !ok || high != (Extreme{Price: 118, Volume: 3500, Minutes: 90}) {
+		t.Errorf("Highest() = %+v, %v", high, ok)
+	}
+	low, ok := hd.Lowest()
+	if !ok || low != (Extreme{Price: 114, Volume: 1200, Minutes: 5}) {
+		t.Errorf("Lowest() = %+v, %v", low, ok)
+	}
+	for _, s := range []string{"", "11800", "11800/x/90", "a/b/c"} {
+		if _, ok := (Historical{High: s}).Highest(); ok {
+			t.Errorf("Highest() of %q reported ok", s)
+		}
+	}
+}
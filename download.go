@@ -0,0 +1,204 @@
+package ib
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HistoryConcurrency is the number of historical data requests the brokerage server allows at once.
+var HistoryConcurrency = 5
+
+// maxHistoryBars is the number of bars the brokerage server returns per request.
+const maxHistoryBars = 1000
+
+// HistoryDownload downloads historical market data over a date range by stitching together multiple requests.
+type HistoryDownload struct {
+	security   Security
+	from       time.Time
+	to         time.Time
+	barSize    int
+	barUnit    TimeUnit
+	outsideRth bool
+	exchange   string
+	onChunk    func(Historical)
+	onProgress func(done int, total int)
+}
+
+// Download creates a download of historical market data of a security between from and to.
+func (s Security) Download(from time.Time, to time.Time, barSize int, barUnit TimeUnit) HistoryDownload {
+	return HistoryDownload{
+		security: s,
+		from:     from,
+		to:       to,
+		barSize:  barSize,
+		barUnit:  barUnit,
+	}
+}
+
+// OutsideRth includes data from outside regular trading hours.
+func (hd HistoryDownload) OutsideRth(outsideRth bool) HistoryDownload {
+	hd.outsideRth = outsideRth
+	return hd
+}
+
+// Exchange requests data from a specific exchange instead of the default.
+func (hd HistoryDownload) Exchange(exchange string) HistoryDownload {
+	hd.exchange = exchange
+	return hd
+}
+
+// OnChunk streams each window of historical data as it arrives.
+// Windows may arrive out of order, the callback is never called concurrently.
+func (hd HistoryDownload) OnChunk(callback func(Historical)) HistoryDownload {
+	hd.onChunk = callback
+	return hd
+}
+
+// OnProgress reports the number of windows downloaded out of the total after each window.
+func (hd HistoryDownload) OnProgress(callback func(done int, total int)) HistoryDownload {
+	hd.onProgress = callback
+	return hd
+}
+
+// window returns the longest period a single request can cover without exceeding the bar limit of the brokerage server,
+// which allows the bar size.
+// Bars are counted over calendar time, so windows are conservative for securities which don't trade around the clock.
+func (hd HistoryDownload) window() (int, TimeUnit, error) {
+	bar := approxDuration(hd.barSize, hd.barUnit)
+	if bar <= 0 {
+		return 0, "", fmt.Errorf("ib: bar unit %q is not supported", hd.barUnit)
+	}
+	span := bar * maxHistoryBars
+	for _, unit := range []TimeUnit{Year, Month, Week, Day, Hour, Min} {
+		n := int(span / approxDuration(1, unit))
+		if n > timeUnitRanges[unit][1] {
+			n = timeUnitRanges[unit][1]
+		}
+		for ; n >= 1; n-- {
+			smallest, largest := allowedBars(approxDuration(n, unit))
+			if bar >= smallest.duration() && bar <= largest.duration() {
+				return n, unit, nil
+			}
+		}
+	}
+	return 0, "", fmt.Errorf("ib: bar size of %d%s is too small to download", hd.barSize, hd.barUnit)
+}
+
+// coveringPeriod returns the shortest period covering d which is at least as long as a bar.
+func coveringPeriod(d time.Duration, bar time.Duration) (int, TimeUnit) {
+	if d < bar {
+		d = bar
+	}
+	for _, unit := range []TimeUnit{Min, Hour, Day, Week, Month, Year} {
+		u := approxDuration(1, unit)
+		n := int((d + u - 1) / u)
+		if n <= timeUnitRanges[unit][1] {
+			return n, unit
+		}
+	}
+	return timeUnitRanges[Year][1], Year
+}
+
+// requests splits the date range into requests which each cover one window, most recent first.
+// Each window starts where the previous one ends, stepping back months and years in calendar time.
+func (hd HistoryDownload) requests() ([]HistoryRequest, error) {
+	period, unit, err := hd.window()
+	if err != nil {
+		return nil, err
+	}
+	if periodStart(hd.to, period, unit).Before(hd.from) {
+		period, unit = coveringPeriod(hd.to.Sub(hd.from), approxDuration(hd.barSize, hd.barUnit))
+	}
+	requests := make([]HistoryRequest, 0)
+	for end := hd.to; end.After(hd.from); end = periodStart(end, period, unit) {
+		hr := hd.security.History(period, unit, hd.barSize, hd.barUnit).
+			StartTime(end).
+			OutsideRth(hd.outsideRth).
+			Exchange(hd.exchange)
+		err := hr.Validate()
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, hr)
+	}
+	return requests, nil
+}
+
+// Fetch downloads every window, at most HistoryConcurrency at a time,
+// and merges them into one Historical with bars between from and to in time order.
+// Bars overlapping between windows are only kept once. High, Low, StartTime and TimePeriod only describe a single window, so they are left empty.
+func (hd HistoryDownload) Fetch() (Historical, error) {
+	requests, err := hd.requests()
+	if err != nil {
+		return Historical{}, err
+	}
+	concurrency := HistoryConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]Historical, len(requests))
+	errs := make([]error, len(requests))
+	sem := make(chan struct{}, concurrency)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	done := 0
+	for i, hr := range requests {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, hr HistoryRequest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i], errs[i] = hr.Fetch()
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			if errs[i] == nil && hd.onChunk != nil {
+				hd.onChunk(results[i])
+			}
+			if hd.onProgress != nil {
+				hd.onProgress(done, len(requests))
+			}
+		}(i, hr)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return Historical{}, err
+		}
+	}
+	return hd.merge(results), nil
+}
+
+// merge combines windows of historical data, de-duplicating bars by time.
+// High, Low, StartTime and TimePeriod of the windows are cleared.
+func (hd HistoryDownload) merge(windows []Historical) Historical {
+	merged := Historical{}
+	if len(windows) > 0 {
+		merged = windows[0]
+	}
+	merged.High = ""
+	merged.Low = ""
+	merged.StartTime = ""
+	merged.TimePeriod = ""
+	from := hd.from.UnixNano() / int64(time.Millisecond)
+	to := hd.to.UnixNano() / int64(time.Millisecond)
+	seen := make(map[int64]bool)
+	data := make([]HistoricalDataFrame, 0)
+	for _, w := range windows {
+		for _, f := range w.Data {
+			if seen[f.T] || f.T < from || f.T > to {
+				continue
+			}
+			seen[f.T] = true
+			data = append(data, f)
+		}
+	}
+	sort.Slice(data, func(i, j int) bool {
+		return data[i].T < data[j].T
+	})
+	merged.Data = data
+	merged.Points = len(data)
+	return merged
+}
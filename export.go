@@ -0,0 +1,536 @@
+package ib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// Format represents a file format data can be exported to and imported from.
+type Format int
+
+const (
+	// CSV files with a header row.
+	CSV Format = iota
+	// JSONLines files with one JSON object per line.
+	JSONLines
+	// Parquet files.
+	Parquet
+)
+
+// barRecord is the exported form of a historical bar, corrected by the price and volume factors.
+type barRecord struct {
+	Time   int64   `json:"time" parquet:"name=time, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Symbol string  `json:"symbol" parquet:"name=symbol, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Open   float64 `json:"open" parquet:"name=open, type=DOUBLE"`
+	High   float64 `json:"high" parquet:"name=high, type=DOUBLE"`
+	Low    float64 `json:"low" parquet:"name=low, type=DOUBLE"`
+	Close  float64 `json:"close" parquet:"name=close, type=DOUBLE"`
+	Volume int64   `json:"volume" parquet:"name=volume, type=INT64"`
+}
+
+// positionRecord is the exported form of a position.
+type positionRecord struct {
+	AcctID            string  `json:"account" parquet:"name=account, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Conid             int64   `json:"conid" parquet:"name=conid, type=INT64"`
+	ContractDesc      string  `json:"contract_desc" parquet:"name=contract_desc, type=BYTE_ARRAY, convertedtype=UTF8"`
+	AssetClass        string  `json:"asset_class" parquet:"name=asset_class, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Currency          string  `json:"currency" parquet:"name=currency, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Position          float64 `json:"position" parquet:"name=position, type=DOUBLE"`
+	MktPrice          float64 `json:"mkt_price" parquet:"name=mkt_price, type=DOUBLE"`
+	MktValue          float64 `json:"mkt_value" parquet:"name=mkt_value, type=DOUBLE"`
+	AvgCost           float64 `json:"avg_cost" parquet:"name=avg_cost, type=DOUBLE"`
+	AvgPrice          float64 `json:"avg_price" parquet:"name=avg_price, type=DOUBLE"`
+	RealizedPnl       float64 `json:"realized_pnl" parquet:"name=realized_pnl, type=DOUBLE"`
+	UnrealizedPnl     float64 `json:"unrealized_pnl" parquet:"name=unrealized_pnl, type=DOUBLE"`
+	BaseMktValue      float64 `json:"base_mkt_value" parquet:"name=base_mkt_value, type=DOUBLE"`
+	BaseRealizedPnl   float64 `json:"base_realized_pnl" parquet:"name=base_realized_pnl, type=DOUBLE"`
+	BaseUnrealizedPnl float64 `json:"base_unrealized_pnl" parquet:"name=base_unrealized_pnl, type=DOUBLE"`
+	Ticker            string  `json:"ticker" parquet:"name=ticker, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Expiry            string  `json:"expiry" parquet:"name=expiry, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Multiplier        string  `json:"multiplier" parquet:"name=multiplier, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Strike            float64 `json:"strike" parquet:"name=strike, type=DOUBLE"`
+	UndConid          int64   `json:"und_conid" parquet:"name=und_conid, type=INT64"`
+}
+
+// snapshotRecord is the exported form of a snapshot.
+// The field tag names the market data field an optional column holds, Volume is kept in its unformatted variant.
+// Fields which weren't part of the snapshot are left empty.
+type snapshotRecord struct {
+	Conid                  int64    `json:"conid" parquet:"name=conid, type=INT64"`
+	Updated                int64    `json:"updated" parquet:"name=updated, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Symbol                 *string  `json:"symbol" field:"55" parquet:"name=symbol, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	LastPrice              *float64 `json:"last_price" field:"31" parquet:"name=last_price, type=DOUBLE, repetitiontype=OPTIONAL"`
+	BidPrice               *float64 `json:"bid_price" field:"84" parquet:"name=bid_price, type=DOUBLE, repetitiontype=OPTIONAL"`
+	AskPrice               *float64 `json:"ask_price" field:"86" parquet:"name=ask_price, type=DOUBLE, repetitiontype=OPTIONAL"`
+	BidSize                *int64   `json:"bid_size" field:"88" parquet:"name=bid_size, type=INT64, repetitiontype=OPTIONAL"`
+	AskSize                *int64   `json:"ask_size" field:"85" parquet:"name=ask_size, type=INT64, repetitiontype=OPTIONAL"`
+	LastSize               *int64   `json:"last_size" field:"7059" parquet:"name=last_size, type=INT64, repetitiontype=OPTIONAL"`
+	Volume                 *float64 `json:"volume" field:"87_raw" parquet:"name=volume, type=DOUBLE, repetitiontype=OPTIONAL"`
+	OpenPrice              *float64 `json:"open_price" field:"7295" parquet:"name=open_price, type=DOUBLE, repetitiontype=OPTIONAL"`
+	High                   *float64 `json:"high" field:"70" parquet:"name=high, type=DOUBLE, repetitiontype=OPTIONAL"`
+	Low                    *float64 `json:"low" field:"71" parquet:"name=low, type=DOUBLE, repetitiontype=OPTIONAL"`
+	ClosePrice             *float64 `json:"close_price" field:"7296" parquet:"name=close_price, type=DOUBLE, repetitiontype=OPTIONAL"`
+	ChangePrice            *float64 `json:"change_price" field:"82" parquet:"name=change_price, type=DOUBLE, repetitiontype=OPTIONAL"`
+	ChangePercent          *float64 `json:"change_percent" field:"83" parquet:"name=change_percent, type=DOUBLE, repetitiontype=OPTIONAL"`
+	MarketDataAvailability *string  `json:"market_data_availability" field:"6509" parquet:"name=market_data_availability, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+}
+
+// executionRecord is the exported form of an execution.
+type executionRecord struct {
+	ExecutionID string  `json:"execution_id" parquet:"name=execution_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Account     string  `json:"account" parquet:"name=account, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Conid       int64   `json:"conid" parquet:"name=conid, type=INT64"`
+	Symbol      string  `json:"symbol" parquet:"name=symbol, type=BYTE_ARRAY, convertedtype=UTF8"`
+	SecType     string  `json:"sec_type" parquet:"name=sec_type, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Side        string  `json:"side" parquet:"name=side, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Size        float64 `json:"size" parquet:"name=size, type=DOUBLE"`
+	Price       float64 `json:"price" parquet:"name=price, type=DOUBLE"`
+	Commission  float64 `json:"commission" parquet:"name=commission, type=DOUBLE"`
+	NetAmount   float64 `json:"net_amount" parquet:"name=net_amount, type=DOUBLE"`
+	Exchange    string  `json:"exchange" parquet:"name=exchange, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TradeTime   int64   `json:"trade_time" parquet:"name=trade_time, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+}
+
+// writeRecords writes a slice of records in a format.
+// Column names are taken from the json tags of the record type.
+func writeRecords(w io.Writer, format Format, records interface{}) error {
+	rv := reflect.ValueOf(records)
+	switch format {
+	case CSV:
+		rt := rv.Type().Elem()
+		cw := csv.NewWriter(w)
+		header := make([]string, rt.NumField())
+		for i := range header {
+			header[i] = rt.Field(i).Tag.Get("json")
+		}
+		err := cw.Write(header)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < rv.Len(); i++ {
+			row := make([]string, len(header))
+			for j := range row {
+				field := rv.Index(i).Field(j)
+				if field.Kind() == reflect.Ptr {
+					if field.IsNil() {
+						continue
+					}
+					field = field.Elem()
+				}
+				row[j] = fmt.Sprint(field.Interface())
+			}
+			err := cw.Write(row)
+			if err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case JSONLines:
+		enc := json.NewEncoder(w)
+		for i := 0; i < rv.Len(); i++ {
+			err := enc.Encode(rv.Index(i).Interface())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case Parquet:
+		pw, err := writer.NewParquetWriterFromWriter(w, reflect.New(rv.Type().Elem()).Interface(), 1)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < rv.Len(); i++ {
+			err := pw.Write(rv.Index(i).Interface())
+			if err != nil {
+				return err
+			}
+		}
+		return pw.WriteStop()
+	}
+	return fmt.Errorf("ib: unknown format %d", format)
+}
+
+// setField parses text into a record field, leaving optional fields nil for empty text.
+func setField(field reflect.Value, text string) error {
+	switch field.Kind() {
+	case reflect.Ptr:
+		if text == "" {
+			return nil
+		}
+		value := reflect.New(field.Type().Elem())
+		err := setField(value.Elem(), text)
+		if err != nil {
+			return err
+		}
+		field.Set(value)
+	case reflect.String:
+		field.SetString(text)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	}
+	return nil
+}
+
+// readRecords reads records in a format into the slice out points to.
+func readRecords(r io.Reader, format Format, out interface{}) error {
+	slice := reflect.ValueOf(out).Elem()
+	rt := slice.Type().Elem()
+	switch format {
+	case CSV:
+		cr := csv.NewReader(r)
+		header, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		columns := make(map[string]int)
+		for i := 0; i < rt.NumField(); i++ {
+			columns[rt.Field(i).Tag.Get("json")] = i
+		}
+		for {
+			row, err := cr.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			record := reflect.New(rt).Elem()
+			for i, text := range row {
+				j, ok := columns[header[i]]
+				if !ok {
+					continue
+				}
+				err := setField(record.Field(j), text)
+				if err != nil {
+					return fmt.Errorf("ib: column %s: %v", header[i], err)
+				}
+			}
+			slice.Set(reflect.Append(slice, record))
+		}
+	case JSONLines:
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			record := reflect.New(rt)
+			err := json.Unmarshal(line, record.Interface())
+			if err != nil {
+				return err
+			}
+			slice.Set(reflect.Append(slice, record.Elem()))
+		}
+		return scanner.Err()
+	case Parquet:
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		pf, err := buffer.NewBufferFile(data)
+		if err != nil {
+			return err
+		}
+		pr, err := reader.NewParquetReader(pf, reflect.New(rt).Interface(), 1)
+		if err != nil {
+			return err
+		}
+		defer pr.ReadStop()
+		records := reflect.New(reflect.SliceOf(rt))
+		records.Elem().Set(reflect.MakeSlice(reflect.SliceOf(rt), int(pr.GetNumRows()), int(pr.GetNumRows())))
+		err = pr.Read(records.Interface())
+		if err != nil {
+			return err
+		}
+		slice.Set(reflect.AppendSlice(slice, records.Elem()))
+		return nil
+	}
+	return fmt.Errorf("ib: unknown format %d", format)
+}
+
+// Export writes the historical data in a format with the columns time, symbol, open, high, low, close and volume.
+// Prices and volumes are corrected by the price and volume factors, see Historical.Bars.
+func (hd Historical) Export(w io.Writer, format Format) error {
+	records := make([]barRecord, 0, len(hd.Data))
+	for _, b := range hd.Bars(time.UTC) {
+		records = append(records, barRecord{
+			Time:   toMillis(b.Time),
+			Symbol: hd.Symbol,
+			Open:   b.Open,
+			High:   b.High,
+			Low:    b.Low,
+			Close:  b.Close,
+			Volume: int64(b.Volume),
+		})
+	}
+	return writeRecords(w, format, records)
+}
+
+// ImportHistorical reads historical data exported by Historical.Export.
+// The data is already corrected, so the price and volume factors are 1.
+func ImportHistorical(r io.Reader, format Format) (Historical, error) {
+	records := make([]barRecord, 0)
+	err := readRecords(r, format, &records)
+	if err != nil {
+		return Historical{}, err
+	}
+	historical := Historical{
+		PriceFactor:  1,
+		VolumeFactor: 1,
+		Data:         make([]HistoricalDataFrame, 0, len(records)),
+	}
+	for _, rec := range records {
+		historical.Symbol = rec.Symbol
+		historical.Data = append(historical.Data, HistoricalDataFrame{
+			T: rec.Time,
+			O: rec.Open,
+			H: rec.High,
+			L: rec.Low,
+			C: rec.Close,
+			V: int(rec.Volume),
+		})
+	}
+	historical.Points = len(historical.Data)
+	return historical, nil
+}
+
+// Export writes the positions in a format.
+func (p Positions) Export(w io.Writer, format Format) error {
+	records := make([]positionRecord, 0, len(p))
+	for _, pos := range p {
+		records = append(records, positionRecord{
+			AcctID:            pos.AcctID,
+			Conid:             int64(pos.Conid),
+			ContractDesc:      pos.ContractDesc,
+			AssetClass:        pos.AssetClass,
+			Currency:          pos.Currency,
+			Position:          pos.Position,
+			MktPrice:          pos.MktPrice,
+			MktValue:          pos.MktValue,
+			AvgCost:           pos.AvgCost,
+			AvgPrice:          pos.AvgPrice,
+			RealizedPnl:       pos.RealizedPnl,
+			UnrealizedPnl:     pos.UnrealizedPnl,
+			BaseMktValue:      pos.BaseMktValue,
+			BaseRealizedPnl:   pos.BaseRealizedPnl,
+			BaseUnrealizedPnl: pos.BaseUnrealizedPnl,
+			Ticker:            pos.Ticker,
+			Expiry:            pos.Expiry,
+			Multiplier:        pos.Multiplier,
+			Strike:            pos.Strike,
+			UndConid:          int64(pos.UndConid),
+		})
+	}
+	return writeRecords(w, format, records)
+}
+
+// ImportPositions reads positions exported by Positions.Export.
+func ImportPositions(r io.Reader, format Format) (Positions, error) {
+	records := make([]positionRecord, 0)
+	err := readRecords(r, format, &records)
+	if err != nil {
+		return nil, err
+	}
+	positions := make(Positions, 0, len(records))
+	for _, rec := range records {
+		positions = append(positions, Position{
+			AcctID:            rec.AcctID,
+			Conid:             int(rec.Conid),
+			ContractDesc:      rec.ContractDesc,
+			AssetClass:        rec.AssetClass,
+			Currency:          rec.Currency,
+			Position:          rec.Position,
+			MktPrice:          rec.MktPrice,
+			MktValue:          rec.MktValue,
+			AvgCost:           rec.AvgCost,
+			AvgPrice:          rec.AvgPrice,
+			RealizedPnl:       rec.RealizedPnl,
+			UnrealizedPnl:     rec.UnrealizedPnl,
+			BaseMktValue:      rec.BaseMktValue,
+			BaseRealizedPnl:   rec.BaseRealizedPnl,
+			BaseUnrealizedPnl: rec.BaseUnrealizedPnl,
+			Ticker:            rec.Ticker,
+			Expiry:            rec.Expiry,
+			Multiplier:        rec.Multiplier,
+			Strike:            rec.Strike,
+			UndConid:          int(rec.UndConid),
+		})
+	}
+	return positions, nil
+}
+
+// snapshotColumns maps the index of every optional column of snapshotRecord to the market data field it holds.
+func snapshotColumns() map[int]MarketDataField {
+	rt := reflect.TypeOf(snapshotRecord{})
+	columns := make(map[int]MarketDataField)
+	for i := 0; i < rt.NumField(); i++ {
+		if f := rt.Field(i).Tag.Get("field"); f != "" {
+			columns[i] = MarketDataField(f)
+		}
+	}
+	return columns
+}
+
+// Export writes the snapshots in a format.
+// Only fields present in Values are written, other columns are left empty.
+func (s Snapshots) Export(w io.Writer, format Format) error {
+	records := make([]snapshotRecord, 0, len(s))
+	columns := snapshotColumns()
+	for _, snap := range s {
+		rec := snapshotRecord{
+			Conid:   int64(snap.Conid),
+			Updated: snap.Updated,
+		}
+		rv := reflect.ValueOf(&rec).Elem()
+		for i, f := range columns {
+			v, ok := snap.Values[f]
+			if !ok {
+				continue
+			}
+			field := rv.Field(i)
+			var err error
+			switch field.Type().Elem().Kind() {
+			case reflect.String:
+				text := v.String()
+				field.Set(reflect.ValueOf(&text))
+			case reflect.Float64:
+				n, isNumber := v.Float()
+				if isNumber {
+					err = setField(field, strconv.FormatFloat(n, 'f', -1, 64))
+				}
+			case reflect.Int64:
+				n, isNumber := v.Int()
+				if isNumber {
+					err = setField(field, strconv.FormatInt(n, 10))
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+		records = append(records, rec)
+	}
+	return writeRecords(w, format, records)
+}
+
+// ImportSnapshots reads snapshots exported by Snapshots.Export.
+// Values holds the imported fields, formatted without IB's prefixes and suffixes.
+// Empty columns are left out of Values.
+// CSV has no null, so a field holding an empty string, e.g. an empty Symbol, is imported as missing.
+func ImportSnapshots(r io.Reader, format Format) (Snapshots, error) {
+	records := make([]snapshotRecord, 0)
+	err := readRecords(r, format, &records)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make(Snapshots, 0, len(records))
+	columns := snapshotColumns()
+	for _, rec := range records {
+		snap := Snapshot{
+			Values: Values{
+				"conid":    Value(strconv.FormatInt(rec.Conid, 10)),
+				"_updated": Value(strconv.FormatInt(rec.Updated, 10)),
+			},
+		}
+		rv := reflect.ValueOf(rec)
+		for i, f := range columns {
+			field := rv.Field(i)
+			if field.IsNil() {
+				continue
+			}
+			snap.Values[f] = Value(fmt.Sprint(field.Elem().Interface()))
+		}
+		snap.decodeFields()
+		snap.Delayed = snap.Status().IsDelayed()
+		snapshots = append(snapshots, snap)
+	}
+	return snapshots, nil
+}
+
+// conid returns the contract id of an execution, parsed from its conidex if needed.
+func (e Execution) conid() int {
+	if e.Conid != 0 {
+		return e.Conid
+	}
+	n, _ := strconv.Atoi(strings.SplitN(e.Conidex, "@", 2)[0])
+	return n
+}
+
+// Export writes the executions in a format.
+func (e Executions) Export(w io.Writer, format Format) error {
+	records := make([]executionRecord, 0, len(e))
+	for _, ex := range e {
+		price, _ := Value(ex.Price).Float()
+		commission, _ := Value(ex.Commission).Float()
+		records = append(records, executionRecord{
+			ExecutionID: ex.ExecutionID,
+			Account:     ex.Account,
+			Conid:       int64(ex.conid()),
+			Symbol:      ex.Symbol,
+			SecType:     ex.SecType,
+			Side:        ex.Side,
+			Size:        ex.Size,
+			Price:       price,
+			Commission:  commission,
+			NetAmount:   ex.NetAmount,
+			Exchange:    ex.Exchange,
+			TradeTime:   ex.TradeTimeR,
+		})
+	}
+	return writeRecords(w, format, records)
+}
+
+// ImportExecutions reads executions exported by Executions.Export.
+func ImportExecutions(r io.Reader, format Format) (Executions, error) {
+	records := make([]executionRecord, 0)
+	err := readRecords(r, format, &records)
+	if err != nil {
+		return nil, err
+	}
+	executions := make(Executions, 0, len(records))
+	for _, rec := range records {
+		executions = append(executions, Execution{
+			ExecutionID: rec.ExecutionID,
+			Account:     rec.Account,
+			Conid:       int(rec.Conid),
+			Conidex:     strconv.FormatInt(rec.Conid, 10),
+			Symbol:      rec.Symbol,
+			SecType:     rec.SecType,
+			Side:        rec.Side,
+			Size:        rec.Size,
+			Price:       strconv.FormatFloat(rec.Price, 'f', -1, 64),
+			Commission:  strconv.FormatFloat(rec.Commission, 'f', -1, 64),
+			NetAmount:   rec.NetAmount,
+			Exchange:    rec.Exchange,
+			TradeTimeR:  rec.TradeTime,
+		})
+	}
+	return executions, nil
+}
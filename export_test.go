@@ -0,0 +1,187 @@
+package ib
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var formats = []struct {
+	name   string
+	format Format
+}{
+	{"csv", CSV},
+	{"json lines", JSONLines},
+	{"parquet", Parquet},
+}
+
+func TestHistoricalExportRoundTrip(t *testing.T) {
+	hd := Historical{
+		Symbol:       "AAPL",
+		PriceFactor:  100,
+		VolumeFactor: 10,
+		Data: []HistoricalDataFrame{
+			{T: 1601904600000, O: 11325, H: 11350, L: 11300, C: 11340, V: 1234},
+			{T: 1601904900000, O: 11340, H: 11380, L: 11330, C: 11375, V: 987},
+		},
+	}
+	for _, f := range formats {
+		var buf bytes.Buffer
+		if err := hd.Export(&buf, f.format); err != nil {
+			t.Fatalf("%s: export: %v", f.name, err)
+		}
+		imported, err := ImportHistorical(&buf, f.format)
+		if err != nil {
+			t.Fatalf("%s: import: %v", f.name, err)
+		}
+		if imported.Symbol != "AAPL" || imported.Points != 2 {
+			t.Errorf("%s: imported symbol %q with %d points", f.name, imported.Symbol, imported.Points)
+		}
+		if got, want := imported.Bars(nil), hd.Bars(nil); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: imported bars %+v, want %+v", f.name, got, want)
+		}
+	}
+}
+
+func TestHistoricalExportCSVColumns(t *testing.T) {
+	hd := Historical{Symbol: "AAPL", Data: []HistoricalDataFrame{{T: 1601904600000, O: 1, H: 2, L: 0.5, C: 1.5, V: 100}}}
+	var buf bytes.Buffer
+	if err := hd.Export(&buf, CSV); err != nil {
+		t.Fatal(err)
+	}
+	want := "time,symbol,open,high,low,close,volume\n1601904600000,AAPL,1,2,0.5,1.5,100\n"
+	if buf.String() != want {
+		t.Errorf("csv export = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPositionsExportRoundTrip(t *testing.T) {
+	positions := Positions{
+		{AcctID: "U1234567", Conid: 265598, ContractDesc: "AAPL", AssetClass: "STK", Currency: "USD", Position: 10, MktPrice: 113.5, AvgCost: 100.25, UnrealizedPnl: 132.5, Ticker: "AAPL"},
+		{AcctID: "U1234567", Conid: 12087792, ContractDesc: "EUR.USD", AssetClass: "CASH", Currency: "USD", Position: -2500.5, Ticker: "EUR, USD"},
+	}
+	for _, f := range formats {
+		var buf bytes.Buffer
+		if err := positions.Export(&buf, f.format); err != nil {
+			t.Fatalf("%s: export: %v", f.name, err)
+		}
+		imported, err := ImportPositions(&buf, f.format)
+		if err != nil {
+			t.Fatalf("%s: import: %v", f.name, err)
+		}
+		if !reflect.DeepEqual(imported, positions) {
+			t.Errorf("%s: imported %+v, want %+v", f.name, imported, positions)
+		}
+	}
+}
+
+func TestSnapshotsExportRoundTrip(t *testing.T) {
+	snapshot := Snapshot{Values: Values{
+		"conid":                Value("265598"),
+		"_updated":             Value("1601904600000"),
+		Symbol:                 Value("AAPL"),
+		LastPrice:              Value("C113.25"),
+		Volume:                 Value("1.2M"),
+		Volume.Raw():           Value("1234567"),
+		ChangePercent:          Value("-1.25%"),
+		MarketDataAvailability: Value("DpB"),
+	}}
+	snapshot.decodeFields()
+	snapshots := Snapshots{snapshot}
+	for _, f := range formats {
+		var buf bytes.Buffer
+		if err := snapshots.Export(&buf, f.format); err != nil {
+			t.Fatalf("%s: export: %v", f.name, err)
+		}
+		imported, err := ImportSnapshots(&buf, f.format)
+		if err != nil {
+			t.Fatalf("%s: import: %v", f.name, err)
+		}
+		if len(imported) != 1 {
+			t.Fatalf("%s: imported %d snapshots, want 1", f.name, len(imported))
+		}
+		got := imported[0]
+		if got.Conid != 265598 || got.Updated != 1601904600000 || got.Symbol != "AAPL" {
+			t.Errorf("%s: imported conid %d, updated %d, symbol %q", f.name, got.Conid, got.Updated, got.Symbol)
+		}
+		if got.LastPrice != 113.25 || got.Volume != 1234567 || got.ChangePercent != -1.25 {
+			t.Errorf("%s: imported last %v, volume %v, change %v", f.name, got.LastPrice, got.Volume, got.ChangePercent)
+		}
+		if !got.Delayed {
+			t.Errorf("%s: imported snapshot isn't delayed", f.name)
+		}
+		if v, ok := got.Field(BidPrice); ok {
+			t.Errorf("%s: Field(BidPrice) = %q, want missing as it was never requested", f.name, v)
+		}
+	}
+}
+
+func TestSnapshotColumnsMatchFields(t *testing.T) {
+	rt := reflect.TypeOf(snapshotRecord{})
+	for i, f := range snapshotColumns() {
+		name := rt.Field(i).Name
+		if got := fieldNames[MarketDataField(strings.TrimSuffix(string(f), "_raw"))]; got != name {
+			t.Errorf("column %s holds field %s, which is %s", name, f, got)
+		}
+	}
+}
+
+func TestSnapshotsExportEmptyString(t *testing.T) {
+	snapshots := Snapshots{{Values: Values{"conid": Value("265598"), Symbol: Value("")}}}
+	tests := []struct {
+		format  Format
+		present bool
+	}{
+		{CSV, false},
+		{JSONLines, true},
+		{Parquet, true},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := snapshots.Export(&buf, tt.format); err != nil {
+			t.Fatal(err)
+		}
+		imported, err := ImportSnapshots(&buf, tt.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := imported[0].Field(Symbol); ok != tt.present {
+			t.Errorf("format %d: empty symbol imported as present %v, want %v", tt.format, ok, tt.present)
+		}
+	}
+}
+
+func TestExecutionsExportRoundTrip(t *testing.T) {
+	executions := Executions{
+		{ExecutionID: "0000e0d5.5f7b0a47.01.01", Account: "U1234567", Conid: 265598, Conidex: "265598", Symbol: "AAPL", SecType: "STK", Side: "B", Size: 10, Price: "113.25", Commission: "1.00", NetAmount: 1132.5, Exchange: "ISLAND", TradeTimeR: 1601904600000},
+		{ExecutionID: "0000e0d5.5f7b0a47.01.02", Account: "U1234567", Conidex: "8314@SMART", Symbol: "IBM", Side: "S", Size: 5, Price: "1,125.5", Commission: "0.35", TradeTimeR: 1601904900000},
+	}
+	for _, f := range formats {
+		var buf bytes.Buffer
+		if err := executions.Export(&buf, f.format); err != nil {
+			t.Fatalf("%s: export: %v", f.name, err)
+		}
+		imported, err := ImportExecutions(&buf, f.format)
+		if err != nil {
+			t.Fatalf("%s: import: %v", f.name, err)
+		}
+		if len(imported) != 2 {
+			t.Fatalf("%s: imported %d executions, want 2", f.name, len(imported))
+		}
+		if imported[1].Conid != 8314 || imported[1].Price != "1125.5" || imported[0].Commission != "1" {
+			t.Errorf("%s: imported %+v", f.name, imported)
+		}
+	}
+}
+
+func TestExecutionsExportNumericColumns(t *testing.T) {
+	var buf bytes.Buffer
+	executions := Executions{{ExecutionID: "1", Conid: 1, Price: "1,125.5", Commission: "0.35"}}
+	if err := executions.Export(&buf, JSONLines); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"price":1125.5`) || !strings.Contains(buf.String(), `"commission":0.35`) {
+		t.Errorf("price and commission aren't exported as numbers: %s", buf.String())
+	}
+}
@@ -0,0 +1,467 @@
+// Code generated by gen_fields.go from fields.csv; DO NOT EDIT.
+
+package ib
+
+const (
+	// LastPrice - The last traded price. Prefixed with C when it is the previous close and H when trading is halted.
+	LastPrice MarketDataField = "31"
+	// Symbol - The symbol of the security.
+	Symbol MarketDataField = "55"
+	// Text - Text describing the security.
+	Text MarketDataField = "58"
+	// High - The highest price of the day.
+	High MarketDataField = "70"
+	// Low - The lowest price of the day.
+	Low MarketDataField = "71"
+	// Pos - The quantity of the position held.
+	Pos MarketDataField = "72"
+	// MarketValue - The market value of the position.
+	MarketValue MarketDataField = "73"
+	// AveragePrice - The average price of the position.
+	AveragePrice MarketDataField = "74"
+	// UnrealizedPnL - The unrealized profit and loss of the position.
+	UnrealizedPnL MarketDataField = "75"
+	// FormattedPosition - The quantity of the position held formatted for display.
+	FormattedPosition MarketDataField = "76"
+	// FormattedUnrealizedPnL - The unrealized profit and loss of the position formatted for display.
+	FormattedUnrealizedPnL MarketDataField = "77"
+	// DailyPnL - The profit and loss of the position for the day.
+	DailyPnL MarketDataField = "78"
+	// ChangePrice - The difference between the last price and the previous close.
+	ChangePrice MarketDataField = "82"
+	// ChangePercent - The difference between the last price and the previous close in percent.
+	ChangePercent MarketDataField = "83"
+	// BidPrice - The highest bid price.
+	BidPrice MarketDataField = "84"
+	// AskSize - The number of contracts or shares offered at the ask price.
+	AskSize MarketDataField = "85"
+	// AskPrice - The lowest ask price.
+	AskPrice MarketDataField = "86"
+	// Volume - The volume of the day.
+	Volume MarketDataField = "87"
+	// BidSize - The number of contracts or shares bid at the bid price.
+	BidSize MarketDataField = "88"
+	// Exchange - The exchange of the security.
+	Exchange MarketDataField = "6004"
+	// Conid - The contract id of the security.
+	Conid MarketDataField = "6008"
+	// SecurityType - The asset class of the security.
+	SecurityType MarketDataField = "6070"
+	// Months - The months with expiring contracts.
+	Months MarketDataField = "6072"
+	// RegularExpiry - The regular expiry dates of the contracts.
+	RegularExpiry MarketDataField = "6073"
+	// MarketDataDeliveryMethodMarker - Marker for the market data delivery method.
+	MarketDataDeliveryMethodMarker MarketDataField = "6119"
+	// UnderlyingConid - The contract id of the underlying security.
+	UnderlyingConid MarketDataField = "6457"
+	// MarketDataAvailability - Whether market data is real time or delayed and the subscription status.
+	MarketDataAvailability MarketDataField = "6509"
+	// CompanyName - The name of the company.
+	CompanyName MarketDataField = "7051"
+	// LastSize - The number of contracts or shares of the last trade.
+	LastSize MarketDataField = "7059"
+	// ConidExchange - The contract id and exchange of the security.
+	ConidExchange MarketDataField = "7094"
+	// ContractDescription - The description of the contract.
+	ContractDescription MarketDataField = "7219"
+	// ContractDescriptionAlt - The alternate description of the contract.
+	ContractDescriptionAlt MarketDataField = "7220"
+	// ListingExchange - The exchange the security is listed on.
+	ListingExchange MarketDataField = "7221"
+	// Industry - The industry of the company.
+	Industry MarketDataField = "7280"
+	// Category - The category of the company.
+	Category MarketDataField = "7281"
+	// AverageDailyVolume - The average volume over 90 days.
+	AverageDailyVolume MarketDataField = "7282"
+	// HistoricVolume30D - The historic volume over 30 days.
+	HistoricVolume30D MarketDataField = "7284"
+	// PutCallRatio - The ratio of put to call volume.
+	PutCallRatio MarketDataField = "7285"
+	// DividendAmount - The amount of the next dividend.
+	DividendAmount MarketDataField = "7286"
+	// DividendYieldPercentage - The expected dividend yield over the next year in percent.
+	DividendYieldPercentage MarketDataField = "7287"
+	// DividendExDate - The ex date of the next dividend.
+	DividendExDate MarketDataField = "7288"
+	// MarketCap - The market capitalization of the company.
+	MarketCap MarketDataField = "7289"
+	// PE - The price to earnings ratio.
+	PE MarketDataField = "7290"
+	// EPS - The earnings per share.
+	EPS MarketDataField = "7291"
+	// CostBasis - The cost basis of the position.
+	CostBasis MarketDataField = "7292"
+	// WeekHigh52 - The highest price over 52 weeks.
+	WeekHigh52 MarketDataField = "7293"
+	// WeekLow52 - The lowest price over 52 weeks.
+	WeekLow52 MarketDataField = "7294"
+	// OpenPrice - The opening price of the day.
+	OpenPrice MarketDataField = "7295"
+	// ClosePrice - The closing price of the previous day.
+	ClosePrice MarketDataField = "7296"
+	// Delta - The delta of the option.
+	Delta MarketDataField = "7308"
+	// Gamma - The gamma of the option.
+	Gamma MarketDataField = "7309"
+	// Theta - The theta of the option.
+	Theta MarketDataField = "7310"
+	// Vega - The vega of the option.
+	Vega MarketDataField = "7311"
+	// ImpliedVolatilityOption - The implied volatility of the option in percent.
+	ImpliedVolatilityOption MarketDataField = "7633"
+)
+
+// fieldNames maps each market data field to its name.
+var fieldNames = map[MarketDataField]string{
+	LastPrice:                      "LastPrice",
+	Symbol:                         "Symbol",
+	Text:                           "Text",
+	High:                           "High",
+	Low:                            "Low",
+	Pos:                            "Pos",
+	MarketValue:                    "MarketValue",
+	AveragePrice:                   "AveragePrice",
+	UnrealizedPnL:                  "UnrealizedPnL",
+	FormattedPosition:              "FormattedPosition",
+	FormattedUnrealizedPnL:         "FormattedUnrealizedPnL",
+	DailyPnL:                       "DailyPnL",
+	ChangePrice:                    "ChangePrice",
+	ChangePercent:                  "ChangePercent",
+	BidPrice:                       "BidPrice",
+	AskSize:                        "AskSize",
+	AskPrice:                       "AskPrice",
+	Volume:                         "Volume",
+	BidSize:                        "BidSize",
+	Exchange:                       "Exchange",
+	Conid:                          "Conid",
+	SecurityType:                   "SecurityType",
+	Months:                         "Months",
+	RegularExpiry:                  "RegularExpiry",
+	MarketDataDeliveryMethodMarker: "MarketDataDeliveryMethodMarker",
+	UnderlyingConid:                "UnderlyingConid",
+	MarketDataAvailability:         "MarketDataAvailability",
+	CompanyName:                    "CompanyName",
+	LastSize:                       "LastSize",
+	ConidExchange:                  "ConidExchange",
+	ContractDescription:            "ContractDescription",
+	ContractDescriptionAlt:         "ContractDescriptionAlt",
+	ListingExchange:                "ListingExchange",
+	Industry:                       "Industry",
+	Category:                       "Category",
+	AverageDailyVolume:             "AverageDailyVolume",
+	HistoricVolume30D:              "HistoricVolume30D",
+	PutCallRatio:                   "PutCallRatio",
+	DividendAmount:                 "DividendAmount",
+	DividendYieldPercentage:        "DividendYieldPercentage",
+	DividendExDate:                 "DividendExDate",
+	MarketCap:                      "MarketCap",
+	PE:                             "PE",
+	EPS:                            "EPS",
+	CostBasis:                      "CostBasis",
+	WeekHigh52:                     "WeekHigh52",
+	WeekLow52:                      "WeekLow52",
+	OpenPrice:                      "OpenPrice",
+	ClosePrice:                     "ClosePrice",
+	Delta:                          "Delta",
+	Gamma:                          "Gamma",
+	Theta:                          "Theta",
+	Vega:                           "Vega",
+	ImpliedVolatilityOption:        "ImpliedVolatilityOption",
+}
+
+// Snapshot stores a market data snapshot of a security.
+// Fields which were not requested or have not arrived are left empty.
+type Snapshot struct {
+	LastPrice                      float64 `json:"31,omitempty"`
+	Symbol                         string  `json:"55,omitempty"`
+	Text                           string  `json:"58,omitempty"`
+	High                           float64 `json:"70,omitempty"`
+	Low                            float64 `json:"71,omitempty"`
+	Position                       float64 `json:"72,omitempty"`
+	MarketValue                    string  `json:"73,omitempty"`
+	AveragePrice                   float64 `json:"74,omitempty"`
+	UnrealizedPnL                  float64 `json:"75,omitempty"`
+	FormattedPosition              string  `json:"76,omitempty"`
+	FormattedUnrealizedPnL         string  `json:"77,omitempty"`
+	DailyPnL                       float64 `json:"78_raw,omitempty"`
+	ChangePrice                    float64 `json:"82,omitempty"`
+	ChangePercent                  float64 `json:"83,omitempty"`
+	BidPrice                       float64 `json:"84,omitempty"`
+	AskSize                        int     `json:"85,omitempty"`
+	AskPrice                       float64 `json:"86,omitempty"`
+	Volume                         float64 `json:"87_raw,omitempty"`
+	BidSize                        int     `json:"88,omitempty"`
+	Exchange                       string  `json:"6004,omitempty"`
+	SecurityType                   string  `json:"6070,omitempty"`
+	Months                         string  `json:"6072,omitempty"`
+	RegularExpiry                  string  `json:"6073,omitempty"`
+	MarketDataDeliveryMethodMarker string  `json:"6119,omitempty"`
+	UnderlyingConid                int     `json:"6457,omitempty"`
+	MarketDataAvailability         string  `json:"6509,omitempty"`
+	CompanyName                    string  `json:"7051,omitempty"`
+	LastSize                       int     `json:"7059,omitempty"`
+	ConidExchange                  string  `json:"7094,omitempty"`
+	ContractDescription            string  `json:"7219,omitempty"`
+	ContractDescriptionAlt         string  `json:"7220,omitempty"`
+	ListingExchange                string  `json:"7221,omitempty"`
+	Industry                       string  `json:"7280,omitempty"`
+	Category                       string  `json:"7281,omitempty"`
+	AverageDailyVolume             string  `json:"7282,omitempty"`
+	HistoricVolume30D              string  `json:"7284,omitempty"`
+	PutCallRatio                   float64 `json:"7285,omitempty"`
+	DividendAmount                 float64 `json:"7286,omitempty"`
+	DividendYieldPercentage        string  `json:"7287,omitempty"`
+	DividendExDate                 string  `json:"7288,omitempty"`
+	MarketCap                      string  `json:"7289,omitempty"`
+	PE                             float64 `json:"7290,omitempty"`
+	EPS                            float64 `json:"7291,omitempty"`
+	CostBasis                      float64 `json:"7292_raw,omitempty"`
+	WeekHigh52                     float64 `json:"7293,omitempty"`
+	WeekLow52                      float64 `json:"7294,omitempty"`
+	OpenPrice                      float64 `json:"7295,omitempty"`
+	ClosePrice                     float64 `json:"7296,omitempty"`
+	Delta                          float64 `json:"7308,omitempty"`
+	Gamma                          float64 `json:"7309,omitempty"`
+	Theta                          float64 `json:"7310,omitempty"`
+	Vega                           float64 `json:"7311,omitempty"`
+	ImpliedVolatilityOption        float64 `json:"7633,omitempty"`
+	Conid                          int     `json:"conid"`
+	ServerID                       string  `json:"server_id,omitempty"`
+	Updated                        int64   `json:"_updated,omitempty"`
+	PreviousClose                  bool    `json:"-"`
+	Halted                         bool    `json:"-"`
+	Delayed                        bool    `json:"-"`
+	Values                         Values  `json:"-"`
+}
+
+// decodeFields sets the struct fields of a snapshot from its values.
+// Values which can't be parsed leave their field empty.
+func (s *Snapshot) decodeFields() {
+	var v Value
+	var ok bool
+	v, ok = s.Values[LastPrice]
+	if ok {
+		s.LastPrice, _ = v.Float()
+	}
+	v, ok = s.Values[Symbol]
+	if ok {
+		s.Symbol = v.String()
+	}
+	v, ok = s.Values[Text]
+	if ok {
+		s.Text = v.String()
+	}
+	v, ok = s.Values[High]
+	if ok {
+		s.High, _ = v.Float()
+	}
+	v, ok = s.Values[Low]
+	if ok {
+		s.Low, _ = v.Float()
+	}
+	v, ok = s.Values[Pos]
+	if ok {
+		s.Position, _ = v.Float()
+	}
+	v, ok = s.Values[MarketValue]
+	if ok {
+		s.MarketValue = v.String()
+	}
+	v, ok = s.Values[AveragePrice]
+	if ok {
+		s.AveragePrice, _ = v.Float()
+	}
+	v, ok = s.Values[UnrealizedPnL]
+	if ok {
+		s.UnrealizedPnL, _ = v.Float()
+	}
+	v, ok = s.Values[FormattedPosition]
+	if ok {
+		s.FormattedPosition = v.String()
+	}
+	v, ok = s.Values[FormattedUnrealizedPnL]
+	if ok {
+		s.FormattedUnrealizedPnL = v.String()
+	}
+	v, ok = s.Field(DailyPnL)
+	if ok {
+		s.DailyPnL, _ = v.Float()
+	}
+	v, ok = s.Values[ChangePrice]
+	if ok {
+		s.ChangePrice, _ = v.Float()
+	}
+	v, ok = s.Values[ChangePercent]
+	if ok {
+		s.ChangePercent, _ = v.Float()
+	}
+	v, ok = s.Values[BidPrice]
+	if ok {
+		s.BidPrice, _ = v.Float()
+	}
+	v, ok = s.Values[AskSize]
+	if ok {
+		n, _ := v.Int()
+		s.AskSize = int(n)
+	}
+	v, ok = s.Values[AskPrice]
+	if ok {
+		s.AskPrice, _ = v.Float()
+	}
+	v, ok = s.Field(Volume)
+	if ok {
+		s.Volume, _ = v.Float()
+	}
+	v, ok = s.Values[BidSize]
+	if ok {
+		n, _ := v.Int()
+		s.BidSize = int(n)
+	}
+	v, ok = s.Values[Exchange]
+	if ok {
+		s.Exchange = v.String()
+	}
+	v, ok = s.Values[SecurityType]
+	if ok {
+		s.SecurityType = v.String()
+	}
+	v, ok = s.Values[Months]
+	if ok {
+		s.Months = v.String()
+	}
+	v, ok = s.Values[RegularExpiry]
+	if ok {
+		s.RegularExpiry = v.String()
+	}
+	v, ok = s.Values[MarketDataDeliveryMethodMarker]
+	if ok {
+		s.MarketDataDeliveryMethodMarker = v.String()
+	}
+	v, ok = s.Values[UnderlyingConid]
+	if ok {
+		n, _ := v.Int()
+		s.UnderlyingConid = int(n)
+	}
+	v, ok = s.Values[MarketDataAvailability]
+	if ok {
+		s.MarketDataAvailability = v.String()
+	}
+	v, ok = s.Values[CompanyName]
+	if ok {
+		s.CompanyName = v.String()
+	}
+	v, ok = s.Values[LastSize]
+	if ok {
+		n, _ := v.Int()
+		s.LastSize = int(n)
+	}
+	v, ok = s.Values[ConidExchange]
+	if ok {
+		s.ConidExchange = v.String()
+	}
+	v, ok = s.Values[ContractDescription]
+	if ok {
+		s.ContractDescription = v.String()
+	}
+	v, ok = s.Values[ContractDescriptionAlt]
+	if ok {
+		s.ContractDescriptionAlt = v.String()
+	}
+	v, ok = s.Values[ListingExchange]
+	if ok {
+		s.ListingExchange = v.String()
+	}
+	v, ok = s.Values[Industry]
+	if ok {
+		s.Industry = v.String()
+	}
+	v, ok = s.Values[Category]
+	if ok {
+		s.Category = v.String()
+	}
+	v, ok = s.Values[AverageDailyVolume]
+	if ok {
+		s.AverageDailyVolume = v.String()
+	}
+	v, ok = s.Values[HistoricVolume30D]
+	if ok {
+		s.HistoricVolume30D = v.String()
+	}
+	v, ok = s.Values[PutCallRatio]
+	if ok {
+		s.PutCallRatio, _ = v.Float()
+	}
+	v, ok = s.Values[DividendAmount]
+	if ok {
+		s.DividendAmount, _ = v.Float()
+	}
+	v, ok = s.Values[DividendYieldPercentage]
+	if ok {
+		s.DividendYieldPercentage = v.String()
+	}
+	v, ok = s.Values[DividendExDate]
+	if ok {
+		s.DividendExDate = v.String()
+	}
+	v, ok = s.Values[MarketCap]
+	if ok {
+		s.MarketCap = v.String()
+	}
+	v, ok = s.Values[PE]
+	if ok {
+		s.PE, _ = v.Float()
+	}
+	v, ok = s.Values[EPS]
+	if ok {
+		s.EPS, _ = v.Float()
+	}
+	v, ok = s.Field(CostBasis)
+	if ok {
+		s.CostBasis, _ = v.Float()
+	}
+	v, ok = s.Values[WeekHigh52]
+	if ok {
+		s.WeekHigh52, _ = v.Float()
+	}
+	v, ok = s.Values[WeekLow52]
+	if ok {
+		s.WeekLow52, _ = v.Float()
+	}
+	v, ok = s.Values[OpenPrice]
+	if ok {
+		s.OpenPrice, _ = v.Float()
+	}
+	v, ok = s.Values[ClosePrice]
+	if ok {
+		s.ClosePrice, _ = v.Float()
+	}
+	v, ok = s.Values[Delta]
+	if ok {
+		s.Delta, _ = v.Float()
+	}
+	v, ok = s.Values[Gamma]
+	if ok {
+		s.Gamma, _ = v.Float()
+	}
+	v, ok = s.Values[Theta]
+	if ok {
+		s.Theta, _ = v.Float()
+	}
+	v, ok = s.Values[Vega]
+	if ok {
+		s.Vega, _ = v.Float()
+	}
+	v, ok = s.Values[ImpliedVolatilityOption]
+	if ok {
+		s.ImpliedVolatilityOption, _ = v.Float()
+	}
+	v, ok = s.Values["conid"]
+	if ok {
+		n, _ := v.Int()
+		s.Conid = int(n)
+	}
+	s.ServerID = s.Values["server_id"].String()
+	s.Updated, _ = s.Values["_updated"].Int()
+}
@@ -0,0 +1,122 @@
+// +build ignore
+
+// This program generates fields_gen.go from the market data field table in fields.csv.
+// Run it with go generate.
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"text/template"
+)
+
+// field is a single row of the market data field table.
+type field struct {
+	ID          string
+	Constant    string
+	Field       string
+	Type        string
+	Raw         bool
+	Description string
+}
+
+var tmpl = template.Must(template.New("fields").Parse(`// Code generated by gen_fields.go from fields.csv; DO NOT EDIT.
+
+package ib
+
+const (
+{{- range .}}
+	// {{.Constant}} - {{.Description}}
+	{{.Constant}} MarketDataField = "{{.ID}}"
+{{- end}}
+)
+
+// fieldNames maps each market data field to its name.
+var fieldNames = map[MarketDataField]string{
+{{- range .}}
+	{{.Constant}}: "{{.Constant}}",
+{{- end}}
+}
+
+// Snapshot stores a market data snapshot of a security.
+// Fields which were not requested or have not arrived are left empty.
+type Snapshot struct {
+{{- range .}}{{if ne .Field "-"}}
+	{{.Field}} {{.Type}} ` + "`" + `json:"{{.ID}}{{if .Raw}}_raw{{end}},omitempty"` + "`" + `
+{{- end}}{{end}}
+	Conid         int    ` + "`" + `json:"conid"` + "`" + `
+	ServerID      string ` + "`" + `json:"server_id,omitempty"` + "`" + `
+	Updated       int64  ` + "`" + `json:"_updated,omitempty"` + "`" + `
+	PreviousClose bool   ` + "`" + `json:"-"` + "`" + `
+	Halted        bool   ` + "`" + `json:"-"` + "`" + `
+	Delayed       bool   ` + "`" + `json:"-"` + "`" + `
+	Values        Values ` + "`" + `json:"-"` + "`" + `
+}
+
+// decodeFields sets the struct fields of a snapshot from its values.
+// Values which can't be parsed leave their field empty.
+func (s *Snapshot) decodeFields() {
+	var v Value
+	var ok bool
+{{- range .}}{{if ne .Field "-"}}
+	{{if .Raw}}v, ok = s.Field({{.Constant}}){{else}}v, ok = s.Values[{{.Constant}}]{{end}}
+	if ok {
+{{- if eq .Type "string"}}
+		s.{{.Field}} = v.String()
+{{- else if eq .Type "float64"}}
+		s.{{.Field}}, _ = v.Float()
+{{- else}}
+		n, _ := v.Int()
+		s.{{.Field}} = {{.Type}}(n)
+{{- end}}
+	}
+{{- end}}{{end}}
+	v, ok = s.Values["conid"]
+	if ok {
+		n, _ := v.Int()
+		s.Conid = int(n)
+	}
+	s.ServerID = s.Values["server_id"].String()
+	s.Updated, _ = s.Values["_updated"].Int()
+}
+`))
+
+func main() {
+	f, err := os.Open("fields.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fields := make([]field, 0)
+	for _, row := range rows[1:] {
+		fields = append(fields, field{
+			ID:          row[0],
+			Constant:    row[1],
+			Field:       row[2],
+			Type:        row[3],
+			Raw:         row[4] == "raw",
+			Description: row[5],
+		})
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, fields)
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = ioutil.WriteFile("fields_gen.go", src, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
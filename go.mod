@@ -3,6 +3,8 @@ module github.com/tomlister/ibclient
 require (
 	github.com/go-resty/resty/v2 v2.3.0
 	github.com/rocketlaunchr/dataframe-go v0.0.0-20201007021539-67b046771f0b
+	github.com/xitongsys/parquet-go v1.6.2
+	github.com/xitongsys/parquet-go-source v0.0.0-20200817004010-026bad9b25d0
 )
 
 go 1.15
@@ -0,0 +1,241 @@
+package ib
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	resty "github.com/go-resty/resty/v2"
+)
+
+// timeUnitRanges are the quantities of each unit the brokerage server accepts for a period.
+var timeUnitRanges = map[TimeUnit][2]int{
+	Min:   {1, 30},
+	Hour:  {1, 8},
+	Day:   {1, 1000},
+	Week:  {1, 792},
+	Month: {1, 182},
+	Year:  {1, 15},
+}
+
+// barSizes are the bar sizes the brokerage server accepts.
+var barSizes = map[TimeUnit][]int{
+	Min:   {1, 2, 3, 5, 10, 15, 30},
+	Hour:  {1, 2, 3, 4, 8},
+	Day:   {1},
+	Week:  {1},
+	Month: {1},
+}
+
+// barSpan is a quantity of a time unit.
+type barSpan struct {
+	n    int
+	unit TimeUnit
+}
+
+func (bs barSpan) duration() time.Duration {
+	return approxDuration(bs.n, bs.unit)
+}
+
+func (bs barSpan) String() string {
+	return strconv.Itoa(bs.n) + string(bs.unit)
+}
+
+// barRanges are the smallest and largest bar sizes the brokerage server allows for periods up to a length,
+// following IBKR's step size table. Periods longer than a year allow the same bar sizes as a year.
+var barRanges = []struct {
+	period barSpan
+	min    barSpan
+	max    barSpan
+}{
+	{barSpan{1, Min}, barSpan{1, Min}, barSpan{1, Min}},
+	{barSpan{1, Hour}, barSpan{1, Min}, barSpan{1, Hour}},
+	{barSpan{4, Hour}, barSpan{1, Min}, barSpan{3, Hour}},
+	{barSpan{8, Hour}, barSpan{1, Min}, barSpan{8, Hour}},
+	{barSpan{1, Day}, barSpan{1, Min}, barSpan{1, Day}},
+	{barSpan{2, Day}, barSpan{2, Min}, barSpan{1, Day}},
+	{barSpan{1, Week}, barSpan{3, Min}, barSpan{1, Week}},
+	{barSpan{1, Month}, barSpan{30, Min}, barSpan{1, Month}},
+	{barSpan{1, Year}, barSpan{1, Day}, barSpan{1, Month}},
+}
+
+// allowedBars returns the smallest and largest bar size allowed for a period.
+func allowedBars(period time.Duration) (barSpan, barSpan) {
+	for _, r := range barRanges {
+		if period <= r.period.duration() {
+			return r.min, r.max
+		}
+	}
+	last := barRanges[len(barRanges)-1]
+	return last.min, last.max
+}
+
+// approxDuration returns the calendar duration of a quantity of a time unit, counting months as 30 days.
+func approxDuration(n int, unit TimeUnit) time.Duration {
+	day := 24 * time.Hour
+	switch unit {
+	case Min:
+		return time.Duration(n) * time.Minute
+	case Hour:
+		return time.Duration(n) * time.Hour
+	case Day:
+		return time.Duration(n) * day
+	case Week:
+		return time.Duration(n) * 7 * day
+	case Month:
+		return time.Duration(n) * 30 * day
+	case Year:
+		return time.Duration(n) * 365 * day
+	}
+	return 0
+}
+
+// periodStart returns the start of a period of n units ending at end, counting months and years in calendar time.
+func periodStart(end time.Time, n int, unit TimeUnit) time.Time {
+	switch unit {
+	case Day:
+		return end.AddDate(0, 0, -n)
+	case Week:
+		return end.AddDate(0, 0, -7*n)
+	case Month:
+		return end.AddDate(0, -n, 0)
+	case Year:
+		return end.AddDate(-n, 0, 0)
+	}
+	return end.Add(-approxDuration(n, unit))
+}
+
+// HistoryRequest builds a request for historical market data of a security.
+type HistoryRequest struct {
+	security   Security
+	period     int
+	periodUnit TimeUnit
+	barSize    int
+	barUnit    TimeUnit
+	outsideRth bool
+	startTime  time.Time
+	exchange   string
+}
+
+// History creates a request for historical market data of a security.
+func (s Security) History(period int, unit TimeUnit, barSize int, barUnit TimeUnit) HistoryRequest {
+	return HistoryRequest{
+		security:   s,
+		period:     period,
+		periodUnit: unit,
+		barSize:    barSize,
+		barUnit:    barUnit,
+	}
+}
+
+// OutsideRth includes data from outside regular trading hours.
+func (hr HistoryRequest) OutsideRth(outsideRth bool) HistoryRequest {
+	hr.outsideRth = outsideRth
+	return hr
+}
+
+// StartTime sets the time the period leads up to, instead of now.
+func (hr HistoryRequest) StartTime(t time.Time) HistoryRequest {
+	hr.startTime = t
+	return hr
+}
+
+// Exchange requests data from a specific exchange instead of the default.
+func (hr HistoryRequest) Exchange(exchange string) HistoryRequest {
+	hr.exchange = exchange
+	return hr
+}
+
+// Validate checks the period and bar size against the ranges the brokerage server accepts.
+func (hr HistoryRequest) Validate() error {
+	r, ok := timeUnitRanges[hr.periodUnit]
+	if !ok {
+		return fmt.Errorf("ib: unknown period unit %q", hr.periodUnit)
+	}
+	if hr.period < r[0] || hr.period > r[1] {
+		return fmt.Errorf("ib: period of %d%s is out of range, must be %d to %d%s", hr.period, hr.periodUnit, r[0], r[1], hr.periodUnit)
+	}
+	sizes, ok := barSizes[hr.barUnit]
+	if !ok {
+		return fmt.Errorf("ib: bar unit %q is not supported", hr.barUnit)
+	}
+	valid := false
+	for _, size := range sizes {
+		if size == hr.barSize {
+			valid = true
+		}
+	}
+	if !valid {
+		return fmt.Errorf("ib: bar size of %d%s is not supported, must be one of %v%s", hr.barSize, hr.barUnit, sizes, hr.barUnit)
+	}
+	bar := approxDuration(hr.barSize, hr.barUnit)
+	period := approxDuration(hr.period, hr.periodUnit)
+	if bar > period {
+		return fmt.Errorf("ib: bar size of %d%s is longer than the period of %d%s", hr.barSize, hr.barUnit, hr.period, hr.periodUnit)
+	}
+	smallest, largest := allowedBars(period)
+	if bar < smallest.duration() || bar > largest.duration() {
+		return fmt.Errorf("ib: bar size of %d%s is not allowed for a period of %d%s, must be %s to %s", hr.barSize, hr.barUnit, hr.period, hr.periodUnit, smallest, largest)
+	}
+	return nil
+}
+
+// query builds the query string of the request.
+func (hr HistoryRequest) query() string {
+	params := url.Values{}
+	params.Set("conid", strconv.Itoa(hr.security.Conid))
+	params.Set("period", strconv.Itoa(hr.period)+string(hr.periodUnit))
+	params.Set("bar", strconv.Itoa(hr.barSize)+string(hr.barUnit))
+	if hr.outsideRth {
+		params.Set("outsideRth", "true")
+	}
+	if !hr.startTime.IsZero() {
+		params.Set("startTime", hr.startTime.UTC().Format("20060102-15:04:05"))
+	}
+	if hr.exchange != "" {
+		params.Set("exchange", hr.exchange)
+	}
+	return params.Encode()
+}
+
+// historyError is the body the brokerage server returns when a historical data request fails.
+type historyError struct {
+	Error string `json:"error"`
+}
+
+// Fetch validates the request and retrieves the historical market data.
+// Nothing is sent to the brokerage server if the request is invalid.
+func (hr HistoryRequest) Fetch() (Historical, error) {
+	err := hr.Validate()
+	if err != nil {
+		return Historical{}, err
+	}
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/iserver/marketdata/history?" + hr.query())
+	if err != nil {
+		return Historical{}, err
+	}
+	he := historyError{}
+	err = json.Unmarshal(resp.Body(), &he)
+	if err == nil && he.Error != "" {
+		return Historical{}, fmt.Errorf("ib: historical data for %d: %s", hr.security.Conid, he.Error)
+	}
+	if resp.IsError() {
+		return Historical{}, fmt.Errorf("ib: historical data for %d: %s: %s", hr.security.Conid, resp.Status(), strings.TrimSpace(string(resp.Body())))
+	}
+	historical := Historical{}
+	err = json.Unmarshal(resp.Body(), &historical)
+	if err != nil {
+		return Historical{}, err
+	}
+	err = checkRealTime(hr.security.Conid, historical.Status())
+	if err != nil {
+		return Historical{}, err
+	}
+	return historical, nil
+}
@@ -0,0 +1,144 @@
+package ib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryRequestValidate(t *testing.T) {
+	s := Security{Conid: 265598}
+	tests := []struct {
+		period  int
+		unit    TimeUnit
+		barSize int
+		barUnit TimeUnit
+		valid   bool
+	}{
+		{1, Day, 5, Min, true},
+		{30, Min, 1, Min, true},
+		{8, Hour, 1, Min, true},
+		{1, Week, 15, Min, true},
+		{1, Month, 1, Hour, true},
+		{1, Year, 1, Day, true},
+		{15, Year, 1, Month, true},
+		{2, Day, 1, Min, false},
+		{1, Week, 2, Min, false},
+		{2, Month, 1, Hour, false},
+		{15, Year, 1, Min, false},
+		{1, Hour, 2, Hour, false},
+		{4, Hour, 4, Hour, false},
+		{0, Day, 1, Min, false},
+		{31, Min, 1, Min, false},
+		{1001, Day, 1, Day, false},
+		{1, Day, 7, Min, false},
+		{1, Day, 1, "s", false},
+		{1, "q", 1, Min, false},
+	}
+	for _, tt := range tests {
+		err := s.History(tt.period, tt.unit, tt.barSize, tt.barUnit).Validate()
+		if (err == nil) != tt.valid {
+			t.Errorf("History(%d, %q, %d, %q).Validate() = %v, want valid %v", tt.period, tt.unit, tt.barSize, tt.barUnit, err, tt.valid)
+		}
+	}
+}
+
+func TestHistoryRequestQuery(t *testing.T) {
+	start := time.Date(2020, 10, 5, 16, 0, 0, 0, time.UTC)
+	hr := Security{Conid: 265598}.History(1, Day, 5, Min).OutsideRth(true).StartTime(start).Exchange("ISLAND")
+	want := "bar=5min&conid=265598&exchange=ISLAND&outsideRth=true&period=1d&startTime=20201005-16%3A00%3A00"
+	if got := hr.query(); got != want {
+		t.Errorf("query() = %s, want %s", got, want)
+	}
+}
+
+func TestDownloadWindowsAreValid(t *testing.T) {
+	to := time.Date(2020, 10, 5, 16, 0, 0, 0, time.UTC)
+	for unit, sizes := range barSizes {
+		for _, size := range sizes {
+			hd := Security{Conid: 265598}.Download(to.AddDate(-3, 0, 0), to, size, unit)
+			if _, err := hd.requests(); err != nil {
+				t.Errorf("Download of %d%s bars: %v", size, unit, err)
+			}
+		}
+	}
+}
+
+func TestHistoryRequestFetchErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   []string
+	}{
+		{http.StatusInternalServerError, `{"error":"Chart data unavailable"}`, []string{"Chart data unavailable"}},
+		{http.StatusServiceUnavailable, "gateway unavailable", []string{"503", "gateway unavailable"}},
+		{http.StatusOK, `{"error":"no bars"}`, []string{"no bars"}},
+	}
+	for _, tt := range tests {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		}))
+		b := base
+		base = server.URL
+		_, err := Security{Conid: 265598}.History(1, Day, 5, Min).Fetch()
+		base = b
+		server.Close()
+		if err == nil {
+			t.Errorf("Fetch with a %d %q response returned no error", tt.status, tt.body)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Fetch with a %d %q response returned %q, want it to contain %q", tt.status, tt.body, err, want)
+			}
+		}
+	}
+}
+
+func TestDownloadWindowsAreContiguous(t *testing.T) {
+	tests := []struct {
+		from    time.Time
+		to      time.Time
+		barSize int
+		barUnit TimeUnit
+	}{
+		{time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC), time.Date(2020, 4, 15, 0, 0, 0, 0, time.UTC), 1, Hour},
+		{time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), 1, Day},
+	}
+	for _, tt := range tests {
+		requests, err := Security{Conid: 265598}.Download(tt.from, tt.to, tt.barSize, tt.barUnit).requests()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !requests[0].startTime.Equal(tt.to) {
+			t.Errorf("%d%s: first window ends at %v, want %v", tt.barSize, tt.barUnit, requests[0].startTime, tt.to)
+		}
+		for i, hr := range requests {
+			start := periodStart(hr.startTime, hr.period, hr.periodUnit)
+			if i+1 < len(requests) && !start.Equal(requests[i+1].startTime) {
+				t.Errorf("%d%s: window %d starts at %v, but the next window ends at %v", tt.barSize, tt.barUnit, i, start, requests[i+1].startTime)
+			}
+			if i+1 == len(requests) && start.After(tt.from) {
+				t.Errorf("%d%s: last window starts at %v, after %v", tt.barSize, tt.barUnit, start, tt.from)
+			}
+		}
+	}
+}
+
+func TestDownloadFetchReturnsWindowErrors(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("gateway unavailable"))
+	}))
+	defer server.Close()
+	defer func(b string) { base = b }(base)
+	base = server.URL
+	to := time.Date(2020, 10, 5, 16, 0, 0, 0, time.UTC)
+	_, err := Security{Broker: BrokerAccount{ID: "U1"}, Conid: 265598}.Download(to.AddDate(0, 0, -10), to, 1, Min).Fetch()
+	if err == nil {
+		t.Error("Fetch returned no error for failing windows")
+	}
+}
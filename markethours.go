@@ -0,0 +1,306 @@
+package ib
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	resty "github.com/go-resty/resty/v2"
+)
+
+// exchangeTimeZones maps IB exchange codes to the time zone their trading schedule is published in.
+var exchangeTimeZones = map[string]string{
+	"SMART":    "America/New_York",
+	"NYSE":     "America/New_York",
+	"NASDAQ":   "America/New_York",
+	"ISLAND":   "America/New_York",
+	"ARCA":     "America/New_York",
+	"AMEX":     "America/New_York",
+	"BATS":     "America/New_York",
+	"IEX":      "America/New_York",
+	"PINK":     "America/New_York",
+	"NYMEX":    "America/New_York",
+	"COMEX":    "America/New_York",
+	"NYBOT":    "America/New_York",
+	"IDEALPRO": "America/New_York",
+	"PAXOS":    "America/New_York",
+	"GLOBEX":   "America/Chicago",
+	"CME":      "America/Chicago",
+	"ECBOT":    "America/Chicago",
+	"CBOT":     "America/Chicago",
+	"CBOE":     "America/Chicago",
+	"CFE":      "America/Chicago",
+	"TSE":      "America/Toronto",
+	"VENTURE":  "America/Toronto",
+	"LSE":      "Europe/London",
+	"ICEEU":    "Europe/London",
+	"IBIS":     "Europe/Berlin",
+	"FWB":      "Europe/Berlin",
+	"EUREX":    "Europe/Berlin",
+	"SBF":      "Europe/Paris",
+	"AEB":      "Europe/Amsterdam",
+	"EBS":      "Europe/Zurich",
+	"SEHK":     "Asia/Hong_Kong",
+	"HKFE":     "Asia/Hong_Kong",
+	"TSEJ":     "Asia/Tokyo",
+	"OSE.JPN":  "Asia/Tokyo",
+	"SGX":      "Asia/Singapore",
+	"ASX":      "Australia/Sydney",
+}
+
+var exchangeTimeZonesMu sync.RWMutex
+
+// SetExchangeTimeZone sets the time zone the trading schedule of an exchange is published in,
+// for exchanges which aren't known yet, e.g. SetExchangeTimeZone("NSE", "Asia/Kolkata").
+func SetExchangeTimeZone(exchange string, zone string) error {
+	_, err := time.LoadLocation(zone)
+	if err != nil {
+		return err
+	}
+	exchangeTimeZonesMu.Lock()
+	defer exchangeTimeZonesMu.Unlock()
+	exchangeTimeZones[exchange] = zone
+	return nil
+}
+
+// ExchangeTimeZone returns the time zone the trading schedule of an exchange is published in.
+func ExchangeTimeZone(exchange string) (*time.Location, error) {
+	exchangeTimeZonesMu.RLock()
+	zone, ok := exchangeTimeZones[exchange]
+	exchangeTimeZonesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("ib: unknown time zone for exchange %s, set it with SetExchangeTimeZone or use TradingScheduleIn", exchange)
+	}
+	return time.LoadLocation(zone)
+}
+
+// Hours represents which trading hours of an exchange to consider.
+type Hours int
+
+const (
+	// RegularHours are the liquid hours of the trading day.
+	RegularHours Hours = iota
+	// ExtendedHours include pre-market and after hours trading.
+	ExtendedHours
+)
+
+// TradingSession stores the open and close time of a single trading session.
+type TradingSession struct {
+	Open  time.Time
+	Close time.Time
+}
+
+// Contains reports whether t falls within the session.
+func (ts TradingSession) Contains(t time.Time) bool {
+	return !t.Before(ts.Open) && t.Before(ts.Close)
+}
+
+// tradingHours stores the opening and closing times of a day in hhmm format.
+type tradingHours struct {
+	OpeningTime string `json:"openingTime"`
+	ClosingTime string `json:"closingTime"`
+}
+
+// tradingDay stores the schedule of a single day as returned from the brokerage server.
+// Dates from 20000101 to 20000107 are templates for every Saturday to Friday,
+// any other date applies to that specific day only.
+type tradingDay struct {
+	ClearingCycleEndTime string         `json:"clearingCycleEndTime"`
+	TradingScheduleDate  string         `json:"tradingScheduleDate"`
+	Sessions             []tradingHours `json:"sessions"`
+	TradingTimes         []tradingHours `json:"tradingTimes"`
+}
+
+// tradingScheduleResponse is a trading schedule for a single trading venue.
+type tradingScheduleResponse struct {
+	ID           string       `json:"id"`
+	TradeVenueID string       `json:"tradeVenueId"`
+	Schedules    []tradingDay `json:"schedules"`
+}
+
+// TradingSchedule stores the trading hours of a security on its exchange.
+type TradingSchedule struct {
+	Exchange string
+	Location *time.Location
+	dates    map[string]tradingDay
+	weekdays map[time.Weekday]tradingDay
+}
+
+// maxScheduleLookahead is the number of days searched for the next session.
+const maxScheduleLookahead = 31
+
+// TradingSchedule retrieves the trading schedule of a security in the time zone of its exchange.
+// An error is returned if the time zone of the exchange isn't known.
+func (s Security) TradingSchedule() (TradingSchedule, error) {
+	info, err := s.info()
+	if err != nil {
+		return TradingSchedule{}, err
+	}
+	loc, err := ExchangeTimeZone(info.Exchange)
+	if err != nil {
+		return TradingSchedule{}, err
+	}
+	return s.tradingSchedule(info, loc)
+}
+
+// TradingScheduleIn retrieves the trading schedule of a security, reading its times in loc.
+func (s Security) TradingScheduleIn(loc *time.Location) (TradingSchedule, error) {
+	info, err := s.info()
+	if err != nil {
+		return TradingSchedule{}, err
+	}
+	return s.tradingSchedule(info, loc)
+}
+
+func (s Security) tradingSchedule(info ContractInfo, loc *time.Location) (TradingSchedule, error) {
+	params := url.Values{}
+	params.Set("assetClass", info.InstrumentType)
+	params.Set("symbol", info.Symbol)
+	params.Set("exchange", info.Exchange)
+	params.Set("exchangeFilter", info.Exchange)
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/trsrv/secdef/schedule?" + params.Encode())
+	if err != nil {
+		return TradingSchedule{}, err
+	}
+	if resp.IsError() {
+		return TradingSchedule{}, fmt.Errorf("ib: trading schedule for %s on %s: %s", info.Symbol, info.Exchange, resp.Status())
+	}
+	venues := []tradingScheduleResponse{}
+	err = json.Unmarshal(resp.Body(), &venues)
+	if err != nil {
+		return TradingSchedule{}, err
+	}
+	schedule := TradingSchedule{
+		Exchange: info.Exchange,
+		Location: loc,
+		dates:    make(map[string]tradingDay),
+		weekdays: make(map[time.Weekday]tradingDay),
+	}
+	if len(venues) == 0 {
+		return schedule, nil
+	}
+	for _, day := range venues[0].Schedules {
+		date, err := time.Parse("20060102", day.TradingScheduleDate)
+		if err != nil {
+			continue
+		}
+		if date.Year() == 2000 && date.Month() == time.January && date.Day() <= 7 {
+			schedule.weekdays[date.Weekday()] = day
+		} else {
+			schedule.dates[day.TradingScheduleDate] = day
+		}
+	}
+	return schedule, nil
+}
+
+// day returns the schedule which applies to the given date.
+func (ts TradingSchedule) day(date time.Time) (tradingDay, bool) {
+	if day, ok := ts.dates[date.Format("20060102")]; ok {
+		return day, true
+	}
+	day, ok := ts.weekdays[date.Weekday()]
+	return day, ok
+}
+
+// clock converts a hhmm time on a date to a time in the schedules location.
+func (ts TradingSchedule) clock(date time.Time, hhmm string) (time.Time, bool) {
+	if len(hhmm) != 4 {
+		return time.Time{}, false
+	}
+	h, err := strconv.Atoi(hhmm[:2])
+	if err != nil {
+		return time.Time{}, false
+	}
+	m, err := strconv.Atoi(hhmm[2:])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), h, m, 0, 0, ts.Location), true
+}
+
+// Sessions returns the trading sessions which close between from and to.
+// Sessions crossing midnight belong to the trading day they close on.
+func (ts TradingSchedule) Sessions(from time.Time, to time.Time, hours Hours) []TradingSession {
+	sessions := make([]TradingSession, 0)
+	if ts.Location == nil {
+		return sessions
+	}
+	from = from.In(ts.Location)
+	to = to.In(ts.Location)
+	date := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, ts.Location)
+	for !date.After(to) {
+		day, ok := ts.day(date)
+		if ok {
+			times := day.Sessions
+			if hours == ExtendedHours || len(times) == 0 {
+				times = day.TradingTimes
+			}
+			for _, t := range times {
+				opening, ok := ts.clock(date, t.OpeningTime)
+				if !ok {
+					continue
+				}
+				closing, ok := ts.clock(date, t.ClosingTime)
+				if !ok {
+					continue
+				}
+				if !closing.After(opening) {
+					opening = opening.AddDate(0, 0, -1)
+				}
+				if closing.Before(from) || closing.After(to) {
+					continue
+				}
+				sessions = append(sessions, TradingSession{Open: opening, Close: closing})
+			}
+		}
+		date = date.AddDate(0, 0, 1)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Open.Before(sessions[j].Open)
+	})
+	return sessions
+}
+
+// upcoming returns the sessions which have not closed yet at t.
+func (ts TradingSchedule) upcoming(t time.Time, hours Hours) []TradingSession {
+	return ts.Sessions(t, t.AddDate(0, 0, maxScheduleLookahead), hours)
+}
+
+// IsOpen reports whether the exchange is open at t.
+func (ts TradingSchedule) IsOpen(t time.Time, hours Hours) bool {
+	for _, session := range ts.upcoming(t, hours) {
+		if session.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns the next time after t the exchange opens.
+// A zero time is returned if the exchange doesn't open within the next month.
+func (ts TradingSchedule) NextOpen(t time.Time, hours Hours) time.Time {
+	for _, session := range ts.upcoming(t, hours) {
+		if session.Open.After(t) {
+			return session.Open
+		}
+	}
+	return time.Time{}
+}
+
+// NextClose returns the next time after t the exchange closes.
+// A zero time is returned if the exchange doesn't close within the next month.
+func (ts TradingSchedule) NextClose(t time.Time, hours Hours) time.Time {
+	for _, session := range ts.upcoming(t, hours) {
+		if session.Close.After(t) {
+			return session.Close
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,223 @@
+package ib
+
+import (
+	"testing"
+	"time"
+)
+
+// scheduleIn returns an empty schedule in a time zone, skipping the test if the zone can't be loaded.
+func scheduleIn(t *testing.T, zone string) TradingSchedule {
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+	return TradingSchedule{
+		Location: loc,
+		dates:    make(map[string]tradingDay),
+		weekdays: make(map[time.Weekday]tradingDay),
+	}
+}
+
+// weekdaySchedule returns a schedule open from 09:30 to 16:00 New York time on weekdays.
+func weekdaySchedule(t *testing.T) TradingSchedule {
+	schedule := scheduleIn(t, "America/New_York")
+	for d := time.Monday; d <= time.Friday; d++ {
+		schedule.weekdays[d] = tradingDay{Sessions: []tradingHours{{OpeningTime: "0930", ClosingTime: "1600"}}}
+	}
+	return schedule
+}
+
+// stockSchedule returns a New York schedule open from 09:30 to 16:00 and extended from 04:00 to 20:00 on weekdays,
+// closed on Monday 12 October 2020 and closing early on Friday 16 October 2020.
+func stockSchedule(t *testing.T) TradingSchedule {
+	schedule := scheduleIn(t, "America/New_York")
+	for d := time.Monday; d <= time.Friday; d++ {
+		schedule.weekdays[d] = tradingDay{
+			Sessions:     []tradingHours{{OpeningTime: "0930", ClosingTime: "1600"}},
+			TradingTimes: []tradingHours{{OpeningTime: "0400", ClosingTime: "2000"}},
+		}
+	}
+	schedule.dates["20201012"] = tradingDay{}
+	schedule.dates["20201016"] = tradingDay{
+		Sessions:     []tradingHours{{OpeningTime: "0930", ClosingTime: "1300"}},
+		TradingTimes: []tradingHours{{OpeningTime: "0400", ClosingTime: "1700"}},
+	}
+	return schedule
+}
+
+// futuresSchedule returns a Chicago schedule trading overnight from 17:00 to 16:00 the next day, Sunday to Friday.
+func futuresSchedule(t *testing.T) TradingSchedule {
+	schedule := scheduleIn(t, "America/Chicago")
+	for d := time.Monday; d <= time.Friday; d++ {
+		schedule.weekdays[d] = tradingDay{TradingTimes: []tradingHours{{OpeningTime: "1700", ClosingTime: "1600"}}}
+	}
+	return schedule
+}
+
+func TestTradingScheduleSessions(t *testing.T) {
+	stocks := stockSchedule(t)
+	ny := stocks.Location
+	futures := futuresSchedule(t)
+	chi := futures.Location
+	tests := []struct {
+		name     string
+		schedule TradingSchedule
+		from     time.Time
+		to       time.Time
+		hours    Hours
+		want     [][2]time.Time
+	}{
+		{
+			name:     "weekend",
+			schedule: stocks,
+			from:     time.Date(2020, 10, 9, 0, 0, 0, 0, ny),
+			to:       time.Date(2020, 10, 13, 0, 0, 0, 0, ny),
+			hours:    RegularHours,
+			want: [][2]time.Time{
+				{time.Date(2020, 10, 9, 9, 30, 0, 0, ny), time.Date(2020, 10, 9, 16, 0, 0, 0, ny)},
+			},
+		},
+		{
+			name:     "holiday and early close",
+			schedule: stocks,
+			from:     time.Date(2020, 10, 12, 0, 0, 0, 0, ny),
+			to:       time.Date(2020, 10, 17, 0, 0, 0, 0, ny),
+			hours:    RegularHours,
+			want: [][2]time.Time{
+				{time.Date(2020, 10, 13, 9, 30, 0, 0, ny), time.Date(2020, 10, 13, 16, 0, 0, 0, ny)},
+				{time.Date(2020, 10, 14, 9, 30, 0, 0, ny), time.Date(2020, 10, 14, 16, 0, 0, 0, ny)},
+				{time.Date(2020, 10, 15, 9, 30, 0, 0, ny), time.Date(2020, 10, 15, 16, 0, 0, 0, ny)},
+				{time.Date(2020, 10, 16, 9, 30, 0, 0, ny), time.Date(2020, 10, 16, 13, 0, 0, 0, ny)},
+			},
+		},
+		{
+			name:     "extended hours",
+			schedule: stocks,
+			from:     time.Date(2020, 10, 16, 0, 0, 0, 0, ny),
+			to:       time.Date(2020, 10, 17, 0, 0, 0, 0, ny),
+			hours:    ExtendedHours,
+			want: [][2]time.Time{
+				{time.Date(2020, 10, 16, 4, 0, 0, 0, ny), time.Date(2020, 10, 16, 17, 0, 0, 0, ny)},
+			},
+		},
+		{
+			name:     "overnight sessions belong to the day they close on",
+			schedule: futures,
+			from:     time.Date(2020, 10, 10, 0, 0, 0, 0, chi),
+			to:       time.Date(2020, 10, 13, 23, 0, 0, 0, chi),
+			hours:    RegularHours,
+			want: [][2]time.Time{
+				{time.Date(2020, 10, 11, 17, 0, 0, 0, chi), time.Date(2020, 10, 12, 16, 0, 0, 0, chi)},
+				{time.Date(2020, 10, 12, 17, 0, 0, 0, chi), time.Date(2020, 10, 13, 16, 0, 0, 0, chi)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		sessions := tt.schedule.Sessions(tt.from, tt.to, tt.hours)
+		if len(sessions) != len(tt.want) {
+			t.Errorf("%s: got %d sessions %v, want %d", tt.name, len(sessions), sessions, len(tt.want))
+			continue
+		}
+		for i, session := range sessions {
+			if !session.Open.Equal(tt.want[i][0]) || !session.Close.Equal(tt.want[i][1]) {
+				t.Errorf("%s: session %d = %v to %v, want %v to %v", tt.name, i, session.Open, session.Close, tt.want[i][0], tt.want[i][1])
+			}
+		}
+	}
+}
+
+func TestTradingScheduleOpenAndClose(t *testing.T) {
+	stocks := stockSchedule(t)
+	ny := stocks.Location
+	futures := futuresSchedule(t)
+	chi := futures.Location
+	tests := []struct {
+		name      string
+		schedule  TradingSchedule
+		at        time.Time
+		hours     Hours
+		open      bool
+		nextOpen  time.Time
+		nextClose time.Time
+	}{
+		{
+			name:      "saturday",
+			schedule:  stocks,
+			at:        time.Date(2020, 10, 10, 12, 0, 0, 0, ny),
+			open:      false,
+			nextOpen:  time.Date(2020, 10, 13, 9, 30, 0, 0, ny),
+			nextClose: time.Date(2020, 10, 13, 16, 0, 0, 0, ny),
+		},
+		{
+			name:      "during the session",
+			schedule:  stocks,
+			at:        time.Date(2020, 10, 13, 10, 0, 0, 0, ny),
+			open:      true,
+			nextOpen:  time.Date(2020, 10, 14, 9, 30, 0, 0, ny),
+			nextClose: time.Date(2020, 10, 13, 16, 0, 0, 0, ny),
+		},
+		{
+			name:      "at the close",
+			schedule:  stocks,
+			at:        time.Date(2020, 10, 16, 13, 0, 0, 0, ny),
+			open:      false,
+			nextOpen:  time.Date(2020, 10, 19, 9, 30, 0, 0, ny),
+			nextClose: time.Date(2020, 10, 19, 16, 0, 0, 0, ny),
+		},
+		{
+			name:      "pre-market in extended hours",
+			schedule:  stocks,
+			at:        time.Date(2020, 10, 13, 5, 0, 0, 0, ny),
+			hours:     ExtendedHours,
+			open:      true,
+			nextOpen:  time.Date(2020, 10, 14, 4, 0, 0, 0, ny),
+			nextClose: time.Date(2020, 10, 13, 20, 0, 0, 0, ny),
+		},
+		{
+			name:      "sunday evening overnight",
+			schedule:  futures,
+			at:        time.Date(2020, 10, 11, 20, 0, 0, 0, chi),
+			open:      true,
+			nextOpen:  time.Date(2020, 10, 12, 17, 0, 0, 0, chi),
+			nextClose: time.Date(2020, 10, 12, 16, 0, 0, 0, chi),
+		},
+		{
+			name:      "daily maintenance break",
+			schedule:  futures,
+			at:        time.Date(2020, 10, 13, 16, 30, 0, 0, chi),
+			open:      false,
+			nextOpen:  time.Date(2020, 10, 13, 17, 0, 0, 0, chi),
+			nextClose: time.Date(2020, 10, 14, 16, 0, 0, 0, chi),
+		},
+	}
+	for _, tt := range tests {
+		if open := tt.schedule.IsOpen(tt.at, tt.hours); open != tt.open {
+			t.Errorf("%s: IsOpen = %v, want %v", tt.name, open, tt.open)
+		}
+		if next := tt.schedule.NextOpen(tt.at, tt.hours); !next.Equal(tt.nextOpen) {
+			t.Errorf("%s: NextOpen = %v, want %v", tt.name, next, tt.nextOpen)
+		}
+		if next := tt.schedule.NextClose(tt.at, tt.hours); !next.Equal(tt.nextClose) {
+			t.Errorf("%s: NextClose = %v, want %v", tt.name, next, tt.nextClose)
+		}
+	}
+}
+
+func TestTradingScheduleLookahead(t *testing.T) {
+	schedule := scheduleIn(t, "America/New_York")
+	loc := schedule.Location
+	now := time.Date(2020, 10, 1, 12, 0, 0, 0, loc)
+	schedule.dates["20201030"] = tradingDay{Sessions: []tradingHours{{OpeningTime: "0930", ClosingTime: "1600"}}}
+	if next := schedule.NextOpen(now, RegularHours); !next.Equal(time.Date(2020, 10, 30, 9, 30, 0, 0, loc)) {
+		t.Errorf("NextOpen = %v, want the session in 29 days", next)
+	}
+	schedule.dates = map[string]tradingDay{
+		"20201105": {Sessions: []tradingHours{{OpeningTime: "0930", ClosingTime: "1600"}}},
+	}
+	if next := schedule.NextOpen(now, RegularHours); !next.IsZero() {
+		t.Errorf("NextOpen = %v, want a zero time for a session beyond %d days", next, maxScheduleLookahead)
+	}
+	if next := schedule.NextClose(now, RegularHours); !next.IsZero() {
+		t.Errorf("NextClose = %v, want a zero time for a session beyond %d days", next, maxScheduleLookahead)
+	}
+}
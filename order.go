@@ -0,0 +1,148 @@
+package ib
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"log"
+
+	resty "github.com/go-resty/resty/v2"
+)
+
+// OrderSide represents the direction of an order.
+type OrderSide string
+
+const (
+	// Buy orders open or add to long positions.
+	Buy OrderSide = "BUY"
+	// Sell orders open or add to short positions.
+	Sell OrderSide = "SELL"
+)
+
+// OrderType represents how an order is executed.
+type OrderType string
+
+const (
+	// Market orders execute at the best available price.
+	Market OrderType = "MKT"
+	// Limit orders execute at the limit price or better.
+	Limit OrderType = "LMT"
+)
+
+// Order stores the parameters of a currency conversion or allocation group order.
+type Order struct {
+	AcctID     string    `json:"acctId,omitempty"`
+	Conid      int       `json:"conid"`
+	SecType    string    `json:"secType,omitempty"`
+	COID       string    `json:"cOID,omitempty"`
+	OrderType  OrderType `json:"orderType"`
+	Side       OrderSide `json:"side"`
+	Price      float64   `json:"price,omitempty"`
+	Quantity   float64   `json:"quantity,omitempty"`
+	Tif        string    `json:"tif"`
+	OutsideRTH bool      `json:"outsideRTH,omitempty"`
+	IsCcyConv  bool      `json:"isCcyConv,omitempty"`
+	FxQty      float64   `json:"fxQty,omitempty"`
+}
+
+// OrderReply stores the response of the brokerage server to an order.
+// Orders which need to be confirmed carry an ID and the Message to confirm with Reply,
+// accepted orders carry an OrderID and OrderStatus.
+type OrderReply struct {
+	ID          string   `json:"id,omitempty"`
+	Message     []string `json:"message,omitempty"`
+	OrderID     string   `json:"order_id,omitempty"`
+	OrderStatus string   `json:"order_status,omitempty"`
+}
+
+type placeOrdersRequest struct {
+	Orders []Order `json:"orders"`
+}
+
+// orderReplies decodes the replies to an order request.
+func orderReplies(body []byte) []OrderReply {
+	replies := make([]OrderReply, 0)
+	err := json.Unmarshal(body, &replies)
+	if err != nil {
+		log.Panic(err)
+	}
+	return replies
+}
+
+// placeOrders submits orders for a brokerage account.
+// Orders without an account are placed for the brokerage account, the callers orders are left unchanged.
+func (ba BrokerAccount) placeOrders(orders []Order) []OrderReply {
+	ba.assertActive()
+	placed := make([]Order, len(orders))
+	for i, order := range orders {
+		if order.AcctID == "" {
+			order.AcctID = ba.ID
+		}
+		placed[i] = order
+	}
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().SetBody(placeOrdersRequest{Orders: placed}).Post(base + "/api/iserver/account/" + ba.ID + "/orders")
+	if err != nil {
+		log.Panic(err)
+	}
+	return orderReplies(resp.Body())
+}
+
+// Reply confirms or rejects an order awaiting confirmation
+func Reply(id string, confirmed bool) []OrderReply {
+	body := map[string]bool{
+		"confirmed": confirmed,
+	}
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().SetBody(body).Post(base + "/api/iserver/reply/" + id)
+	if err != nil {
+		log.Panic(err)
+	}
+	return orderReplies(resp.Body())
+}
+
+// Execution stores a single trade execution.
+type Execution struct {
+	ExecutionID         string  `json:"execution_id"`
+	Symbol              string  `json:"symbol"`
+	Side                string  `json:"side"`
+	OrderDescription    string  `json:"order_description"`
+	TradeTime           string  `json:"trade_time"`
+	TradeTimeR          int64   `json:"trade_time_r"`
+	Size                float64 `json:"size"`
+	Price               string  `json:"price"`
+	Submitter           string  `json:"submitter"`
+	Exchange            string  `json:"exchange"`
+	Commission          string  `json:"commission"`
+	NetAmount           float64 `json:"net_amount"`
+	Account             string  `json:"account"`
+	CompanyName         string  `json:"companyName"`
+	ContractDescription string  `json:"contract_description_1"`
+	SecType             string  `json:"sec_type"`
+	Conidex             string  `json:"conidex"`
+	Conid               int     `json:"conid"`
+	ClearingID          string  `json:"clearing_id"`
+	ClearingName        string  `json:"clearing_name"`
+}
+
+// Executions is an array of executions
+type Executions []Execution
+
+// Trades retrieves the executions of the brokerage account of the current and previous six days.
+// The brokerage server returns the trades of the active account, so the account must be active.
+func (ba BrokerAccount) Trades() Executions {
+	ba.assertActive()
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/iserver/account/trades")
+	if err != nil {
+		log.Panic(err)
+	}
+	executions := Executions{}
+	err = json.Unmarshal(resp.Body(), &executions)
+	if err != nil {
+		log.Panic(err)
+	}
+	return executions
+}
@@ -0,0 +1,71 @@
+package ib
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	resty "github.com/go-resty/resty/v2"
+)
+
+// AccountPnL stores the profit and loss of a single account.
+type AccountPnL struct {
+	Key             string  `json:"-"`
+	RowType         int     `json:"rowType"`
+	DailyPnL        float64 `json:"dpl"`
+	NetLiquidation  float64 `json:"nl"`
+	UnrealizedPnL   float64 `json:"upl"`
+	ExcessLiquidity float64 `json:"el"`
+	MarginValue     float64 `json:"mv"`
+}
+
+// AccountID returns the id of the account, dropping the model suffix of the key, e.g. "U123.Core".
+func (ap AccountPnL) AccountID() string {
+	return strings.SplitN(ap.Key, ".", 2)[0]
+}
+
+// PartitionedPnL stores the profit and loss of all accounts keyed by account and model.
+type PartitionedPnL map[string]AccountPnL
+
+type partitionedPnLResponse struct {
+	UPnL PartitionedPnL `json:"upnl"`
+}
+
+// PnL retrieves the profit and loss of all accounts
+func PnL() PartitionedPnL {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/iserver/account/pnl/partitioned")
+	if err != nil {
+		log.Panic(err)
+	}
+	pnl := partitionedPnLResponse{}
+	err = json.Unmarshal(resp.Body(), &pnl)
+	if err != nil {
+		log.Panic(err)
+	}
+	partitioned := PartitionedPnL{}
+	for key, ap := range pnl.UPnL {
+		ap.Key = key
+		partitioned[key] = ap
+	}
+	return partitioned
+}
+
+// WatchPnL polls the profit and loss of all accounts periodically
+// and calls back with each account whose values changed since the last poll.
+// Closing the returned channel stops polling.
+func WatchPnL(callback func(AccountPnL), interval time.Duration) chan struct{} {
+	last := PartitionedPnL{}
+	return Schedule(func() {
+		for key, ap := range PnL() {
+			if prev, ok := last[key]; ok && prev == ap {
+				continue
+			}
+			last[key] = ap
+			callback(ap)
+		}
+	}, interval)
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"log"
+	"strconv"
 
 	resty "github.com/go-resty/resty/v2"
 )
@@ -129,3 +130,97 @@ func (p Positions) FilterAssets(a AssetClass) Positions {
 	}
 	return tmp
 }
+
+// AllocationBreakdown stores the long and short market value of a portfolio keyed by category.
+type AllocationBreakdown struct {
+	Long  map[string]float64 `json:"long"`
+	Short map[string]float64 `json:"short"`
+}
+
+// Allocation stores the market value of a portfolio broken down by asset class, sector and group.
+type Allocation struct {
+	AssetClass AllocationBreakdown `json:"assetClass"`
+	Sector     AllocationBreakdown `json:"sector"`
+	Group      AllocationBreakdown `json:"group"`
+}
+
+// Allocation retrieves the allocation of a portfolio as calculated by the brokerage server
+func (p Portfolio) Allocation() Allocation {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/portfolio/" + p.AccountID + "/allocation")
+	if err != nil {
+		log.Panic(err)
+	}
+	allocation := Allocation{}
+	err = json.Unmarshal(resp.Body(), &allocation)
+	if err != nil {
+		log.Panic(err)
+	}
+	return allocation
+}
+
+// unclassified is the category positions without an asset class, sector or group are allocated to.
+const unclassified = "Others"
+
+// category converts an untyped position category to its name.
+func category(v interface{}) string {
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return unclassified
+	}
+	return s
+}
+
+// add allocates a market value to a category.
+func (ab *AllocationBreakdown) add(key string, value float64) {
+	if value < 0 {
+		ab.Short[key] += value
+	} else {
+		ab.Long[key] += value
+	}
+}
+
+// Allocation calculates the allocation of positions in the base currency.
+// Unlike Portfolio.Allocation it can be used on a filtered subset of positions.
+func (p Positions) Allocation() Allocation {
+	allocation := Allocation{}
+	for _, ab := range []*AllocationBreakdown{&allocation.AssetClass, &allocation.Sector, &allocation.Group} {
+		ab.Long = make(map[string]float64)
+		ab.Short = make(map[string]float64)
+	}
+	for _, pos := range p {
+		allocation.AssetClass.add(category(pos.AssetClass), pos.BaseMktValue)
+		allocation.Sector.add(category(pos.Sector), pos.BaseMktValue)
+		allocation.Group.add(category(pos.Group), pos.BaseMktValue)
+	}
+	return allocation
+}
+
+// Position retrieves a single position of a portfolio by contract id
+func (p Portfolio) Position(conid int) Positions {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/portfolio/" + p.AccountID + "/position/" + strconv.Itoa(conid))
+	if err != nil {
+		log.Panic(err)
+	}
+	positions := Positions{}
+	err = json.Unmarshal(resp.Body(), &positions)
+	if err != nil {
+		log.Panic(err)
+	}
+	return positions
+}
+
+// InvalidatePositions invalidates the positions cached by the brokerage server.
+// The gateway caches positions, so this should be called after trades
+// before retrieving positions again.
+func (p Portfolio) InvalidatePositions() {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	_, err := client.R().Post(base + "/api/portfolio/" + p.AccountID + "/positions/invalidate")
+	if err != nil {
+		log.Panic(err)
+	}
+}
@@ -0,0 +1,302 @@
+package ib
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ResampleOption changes how bars are resampled.
+type ResampleOption func(*resampleOptions)
+
+type resampleOptions struct {
+	schedule    *TradingSchedule
+	hours       Hours
+	dropPartial bool
+}
+
+// AlignToSessions aligns resampled bars to the open of each trading session of a schedule instead of to UTC.
+// Intraday bars never span two sessions, the last bar of a session ends at its close.
+// Bars outside the sessions are left out.
+func AlignToSessions(schedule TradingSchedule, hours Hours) ResampleOption {
+	return func(o *resampleOptions) {
+		o.schedule = &schedule
+		o.hours = hours
+	}
+}
+
+// DropPartial leaves out the final resampled bar if the bars it was built from don't cover it entirely,
+// e.g. a 15 minute bar built from the first 5 minutes of the period while the market is open.
+// Resampling fails if the size of the bars being resampled is unknown, e.g. a single frame without a BarLength.
+func DropPartial() ResampleOption {
+	return func(o *resampleOptions) {
+		o.dropPartial = true
+	}
+}
+
+// bucketFunc returns the start and end of the resampled bar a time falls in.
+type bucketFunc func(t time.Time) (start time.Time, end time.Time, ok bool)
+
+// sortedCopy returns a copy of frames in time order.
+func sortedCopy(frames []HistoricalDataFrame) []HistoricalDataFrame {
+	sorted := make([]HistoricalDataFrame, len(frames))
+	copy(sorted, frames)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].T < sorted[j].T
+	})
+	return sorted
+}
+
+// inferBarDuration returns the shortest time between two consecutive frames.
+func inferBarDuration(frames []HistoricalDataFrame) time.Duration {
+	var d time.Duration
+	for i := 1; i < len(frames); i++ {
+		gap := time.Duration(frames[i].T-frames[i-1].T) * time.Millisecond
+		if gap > 0 && (d == 0 || gap < d) {
+			d = gap
+		}
+	}
+	return d
+}
+
+// aggregate combines frames in time order into one frame per bucket.
+// The open is the first open, the close the last close, the high and low the extremes and the volume the sum.
+func aggregate(frames []HistoricalDataFrame, source time.Duration, bucket bucketFunc, dropPartial bool) []HistoricalDataFrame {
+	resampled := make([]HistoricalDataFrame, 0)
+	var end time.Time
+	var last HistoricalDataFrame
+	for _, f := range frames {
+		start, e, ok := bucket(f.Time())
+		if !ok {
+			continue
+		}
+		t := start.UnixNano() / int64(time.Millisecond)
+		n := len(resampled)
+		if n > 0 && resampled[n-1].T == t {
+			bar := &resampled[n-1]
+			if f.H > bar.H {
+				bar.H = f.H
+			}
+			if f.L < bar.L {
+				bar.L = f.L
+			}
+			bar.C = f.C
+			bar.V += f.V
+		} else {
+			resampled = append(resampled, HistoricalDataFrame{
+				T: t,
+				O: f.O,
+				H: f.H,
+				L: f.L,
+				C: f.C,
+				V: f.V,
+			})
+		}
+		end = e
+		last = f
+	}
+	if dropPartial && len(resampled) > 0 && last.Time().Add(source).Before(end) {
+		resampled = resampled[:len(resampled)-1]
+	}
+	return resampled
+}
+
+// calendarStart returns the start of the day, week (from Monday) or month a time falls in.
+func calendarStart(t time.Time, unit TimeUnit) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch unit {
+	case Week:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case Month:
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+// calendarEnd returns the start of the next day, week or month.
+func calendarEnd(start time.Time, unit TimeUnit) time.Time {
+	switch unit {
+	case Week:
+		return start.AddDate(0, 0, 7)
+	case Month:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// sessionIndex finds the session containing t in sessions sorted by open.
+func sessionIndex(sessions []TradingSession, t time.Time) (int, bool) {
+	i := sort.Search(len(sessions), func(i int) bool {
+		return sessions[i].Close.After(t)
+	})
+	if i < len(sessions) && sessions[i].Contains(t) {
+		return i, true
+	}
+	return 0, false
+}
+
+// scheduledSessions returns the sessions of a schedule around the frames,
+// from the start of the day, week or month of the first frame and far enough ahead to complete the last month.
+func scheduledSessions(frames []HistoricalDataFrame, schedule TradingSchedule, hours Hours, unit TimeUnit) []TradingSession {
+	if len(frames) == 0 || schedule.Location == nil {
+		return nil
+	}
+	from := calendarStart(frames[0].Time().In(schedule.Location), unit)
+	to := frames[len(frames)-1].Time().AddDate(0, 0, maxScheduleLookahead)
+	return schedule.Sessions(from, to, hours)
+}
+
+// groupedSessionBuckets buckets times by the sessions of a schedule, grouping every session by key.
+// A bucket runs from the open of its first session to the close of its last.
+func groupedSessionBuckets(sessions []TradingSession, key func(TradingSession) time.Time) bucketFunc {
+	starts := make(map[time.Time]time.Time)
+	ends := make(map[time.Time]time.Time)
+	for _, session := range sessions {
+		k := key(session)
+		if start, ok := starts[k]; !ok || session.Open.Before(start) {
+			starts[k] = session.Open
+		}
+		if end, ok := ends[k]; !ok || session.Close.After(end) {
+			ends[k] = session.Close
+		}
+	}
+	return func(t time.Time) (time.Time, time.Time, bool) {
+		i, ok := sessionIndex(sessions, t)
+		if !ok {
+			return time.Time{}, time.Time{}, false
+		}
+		k := key(sessions[i])
+		return starts[k], ends[k], true
+	}
+}
+
+// ResampleFrames aggregates frames into bars of a longer size, e.g. 1 minute bars into 15 minute bars.
+// Intraday bar sizes must be a multiple of the size of the frames, which is inferred from the shortest gap between them.
+// Daily, weekly and monthly bars are only supported with a size of 1.
+// Without AlignToSessions bars are aligned to UTC and weeks start on Monday.
+func ResampleFrames(frames []HistoricalDataFrame, barSize int, barUnit TimeUnit, opts ...ResampleOption) ([]HistoricalDataFrame, error) {
+	sorted := sortedCopy(frames)
+	return resample(sorted, inferBarDuration(sorted), barSize, barUnit, opts...)
+}
+
+func resample(frames []HistoricalDataFrame, source time.Duration, barSize int, barUnit TimeUnit, opts ...ResampleOption) ([]HistoricalDataFrame, error) {
+	options := resampleOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if _, ok := barSizes[barUnit]; !ok {
+		return nil, fmt.Errorf("ib: bar unit %q is not supported", barUnit)
+	}
+	if barSize < 1 {
+		return nil, fmt.Errorf("ib: bar size of %d%s is not supported, must be at least 1%s", barSize, barUnit, barUnit)
+	}
+	target := approxDuration(barSize, barUnit)
+	if target < source {
+		return nil, fmt.Errorf("ib: bar size of %d%s is shorter than the bars being resampled", barSize, barUnit)
+	}
+	intraday := barUnit == Min || barUnit == Hour
+	if intraday && source > 0 && target%source != 0 {
+		return nil, fmt.Errorf("ib: bar size of %d%s is not a multiple of the bars being resampled", barSize, barUnit)
+	}
+	if !intraday && barSize != 1 {
+		return nil, fmt.Errorf("ib: bar size of %d%s is not supported, must be 1%s", barSize, barUnit, barUnit)
+	}
+	if options.dropPartial && source <= 0 {
+		return nil, fmt.Errorf("ib: the size of the bars being resampled is unknown, so partial bars can't be dropped")
+	}
+	var bucket bucketFunc
+	switch {
+	case options.schedule == nil && intraday:
+		bucket = func(t time.Time) (time.Time, time.Time, bool) {
+			start := t.UTC().Truncate(target)
+			return start, start.Add(target), true
+		}
+	case options.schedule == nil:
+		bucket = func(t time.Time) (time.Time, time.Time, bool) {
+			start := calendarStart(t.UTC(), barUnit)
+			return start, calendarEnd(start, barUnit), true
+		}
+	case intraday:
+		sessions := scheduledSessions(frames, *options.schedule, options.hours, Day)
+		bucket = func(t time.Time) (time.Time, time.Time, bool) {
+			i, ok := sessionIndex(sessions, t)
+			if !ok {
+				return time.Time{}, time.Time{}, false
+			}
+			session := sessions[i]
+			start := session.Open.Add(t.Sub(session.Open) / target * target)
+			end := start.Add(target)
+			if end.After(session.Close) {
+				end = session.Close
+			}
+			return start, end, true
+		}
+	default:
+		sessions := scheduledSessions(frames, *options.schedule, options.hours, barUnit)
+		loc := options.schedule.Location
+		bucket = groupedSessionBuckets(sessions, func(session TradingSession) time.Time {
+			return calendarStart(session.Close.In(loc), barUnit)
+		})
+	}
+	return aggregate(frames, source, bucket, options.dropPartial), nil
+}
+
+// ResampleSessionFrames aggregates frames into one bar per trading session of a schedule.
+// Frames outside the sessions are left out.
+func ResampleSessionFrames(frames []HistoricalDataFrame, schedule TradingSchedule, hours Hours, opts ...ResampleOption) ([]HistoricalDataFrame, error) {
+	sorted := sortedCopy(frames)
+	return resampleSessions(sorted, inferBarDuration(sorted), schedule, hours, opts...)
+}
+
+func resampleSessions(frames []HistoricalDataFrame, source time.Duration, schedule TradingSchedule, hours Hours, opts ...ResampleOption) ([]HistoricalDataFrame, error) {
+	options := resampleOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.dropPartial && source <= 0 {
+		return nil, fmt.Errorf("ib: the size of the bars being resampled is unknown, so partial bars can't be dropped")
+	}
+	sessions := scheduledSessions(frames, schedule, hours, Day)
+	bucket := func(t time.Time) (time.Time, time.Time, bool) {
+		i, ok := sessionIndex(sessions, t)
+		if !ok {
+			return time.Time{}, time.Time{}, false
+		}
+		return sessions[i].Open, sessions[i].Close, true
+	}
+	return aggregate(frames, source, bucket, options.dropPartial), nil
+}
+
+// barDuration returns the length of the historical bars, inferring it from the data if the brokerage server didn't report it.
+func (hd Historical) barDuration() time.Duration {
+	if hd.BarLength > 0 {
+		return time.Duration(hd.BarLength) * time.Second
+	}
+	return inferBarDuration(hd.Data)
+}
+
+// Resample aggregates the historical data into bars of a longer size without requesting it again.
+// See ResampleFrames.
+func (hd Historical) Resample(barSize int, barUnit TimeUnit, opts ...ResampleOption) (Historical, error) {
+	sorted := sortedCopy(hd.Data)
+	data, err := resample(sorted, hd.barDuration(), barSize, barUnit, opts...)
+	if err != nil {
+		return Historical{}, err
+	}
+	hd.Data = data
+	hd.Points = len(data)
+	hd.BarLength = int(approxDuration(barSize, barUnit) / time.Second)
+	return hd, nil
+}
+
+// ResampleSessions aggregates the historical data into one bar per trading session of a schedule.
+func (hd Historical) ResampleSessions(schedule TradingSchedule, hours Hours, opts ...ResampleOption) (Historical, error) {
+	data, err := resampleSessions(sortedCopy(hd.Data), hd.barDuration(), schedule, hours, opts...)
+	if err != nil {
+		return Historical{}, err
+	}
+	hd.Data = data
+	hd.Points = len(data)
+	hd.BarLength = 0
+	return hd, nil
+}
@@ -0,0 +1,210 @@
+package ib
+
+import (
+	"testing"
+	"time"
+)
+
+// minuteFrames returns a 1 minute frame for every minute from start, with prices rising by one each minute.
+func minuteFrames(start time.Time, minutes int) []HistoricalDataFrame {
+	frames := make([]HistoricalDataFrame, 0, minutes)
+	for i := 0; i < minutes; i++ {
+		p := float64(i)
+		frames = append(frames, HistoricalDataFrame{
+			T: toMillis(start.Add(time.Duration(i) * time.Minute)),
+			O: p,
+			H: p + 0.5,
+			L: p - 0.5,
+			C: p + 0.25,
+			V: i + 1,
+		})
+	}
+	return frames
+}
+
+func TestResampleAggregatesOHLCV(t *testing.T) {
+	start := time.Date(2020, 10, 5, 13, 30, 0, 0, time.UTC)
+	frames := minuteFrames(start, 10)
+	// Shuffle the order, resampling sorts frames by time.
+	frames[0], frames[9] = frames[9], frames[0]
+	bars, err := ResampleFrames(frames, 5, Min)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []HistoricalDataFrame{
+		{T: toMillis(start), O: 0, H: 4.5, L: -0.5, C: 4.25, V: 1 + 2 + 3 + 4 + 5},
+		{T: toMillis(start.Add(5 * time.Minute)), O: 5, H: 9.5, L: 4.5, C: 9.25, V: 6 + 7 + 8 + 9 + 10},
+	}
+	if len(bars) != len(want) {
+		t.Fatalf("resampled %d bars, want %d", len(bars), len(want))
+	}
+	for i := range want {
+		if bars[i] != want[i] {
+			t.Errorf("bar %d = %+v, want %+v", i, bars[i], want[i])
+		}
+	}
+}
+
+func TestResampleErrors(t *testing.T) {
+	frames := minuteFrames(time.Date(2020, 10, 5, 13, 30, 0, 0, time.UTC), 10)
+	tests := []struct {
+		barSize int
+		barUnit TimeUnit
+	}{
+		{2, Week},
+		{0, Min},
+		{2, Day},
+		{1, "s"},
+	}
+	for _, tt := range tests {
+		if _, err := ResampleFrames(frames, tt.barSize, tt.barUnit); err == nil {
+			t.Errorf("ResampleFrames of 1min frames into %d%s succeeded, want an error", tt.barSize, tt.barUnit)
+		}
+	}
+	five, _ := ResampleFrames(frames, 5, Min)
+	if _, err := ResampleFrames(five, 3, Min); err == nil {
+		t.Error("ResampleFrames of 5min frames into 3min succeeded, want an error")
+	}
+}
+
+func TestResampleAlignsToSessionOpen(t *testing.T) {
+	schedule := weekdaySchedule(t)
+	loc := schedule.Location
+	open := time.Date(2020, 10, 5, 9, 30, 0, 0, loc)
+	// A full session of 390 minutes plus pre-market bars, which are left out.
+	frames := minuteFrames(open.Add(-30*time.Minute), 420)
+	bars, err := ResampleFrames(frames, 1, Hour, AlignToSessions(schedule, RegularHours))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bars) != 7 {
+		t.Fatalf("resampled %d bars, want 7", len(bars))
+	}
+	for i, b := range bars {
+		want := open.Add(time.Duration(i) * time.Hour)
+		if !b.Time().Equal(want) {
+			t.Errorf("bar %d starts at %v, want %v", i, b.Time().In(loc), want)
+		}
+	}
+	if last := bars[6]; last.V != sumVolume(frames, open.Add(6*time.Hour), open.Add(390*time.Minute)) {
+		t.Errorf("last bar of the session has volume %d, want the volume of 15:30 to 16:00", last.V)
+	}
+	// The last bar ends at the close, so it isn't partial.
+	complete, _ := ResampleFrames(frames, 1, Hour, AlignToSessions(schedule, RegularHours), DropPartial())
+	if len(complete) != 7 {
+		t.Errorf("DropPartial dropped the last bar of a complete session, %d bars left", len(complete))
+	}
+}
+
+func sumVolume(frames []HistoricalDataFrame, from time.Time, to time.Time) int {
+	v := 0
+	for _, f := range frames {
+		if !f.Time().Before(from) && f.Time().Before(to) {
+			v += f.V
+		}
+	}
+	return v
+}
+
+func TestResampleDropPartial(t *testing.T) {
+	start := time.Date(2020, 10, 5, 13, 30, 0, 0, time.UTC)
+	frames := minuteFrames(start, 20)
+	all, _ := ResampleFrames(frames, 15, Min)
+	if len(all) != 2 {
+		t.Fatalf("resampled %d bars, want 2", len(all))
+	}
+	complete, _ := ResampleFrames(frames, 15, Min, DropPartial())
+	if len(complete) != 1 || complete[0].T != toMillis(start) {
+		t.Errorf("DropPartial kept %+v, want only the complete first bar", complete)
+	}
+	full, _ := ResampleFrames(minuteFrames(start, 30), 15, Min, DropPartial())
+	if len(full) != 2 {
+		t.Errorf("DropPartial dropped a complete bar, %d bars left", len(full))
+	}
+}
+
+func TestResampleSessionsAndDays(t *testing.T) {
+	schedule := weekdaySchedule(t)
+	loc := schedule.Location
+	monday := time.Date(2020, 10, 5, 9, 30, 0, 0, loc)
+	frames := append(minuteFrames(monday, 390), minuteFrames(monday.AddDate(0, 0, 1), 60)...)
+	hd := Historical{Data: frames, BarLength: 60}
+	sessions, err := hd.ResampleSessions(schedule, RegularHours)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sessions.Points != 2 || !sessions.Data[1].Time().Equal(monday.AddDate(0, 0, 1)) {
+		t.Fatalf("resampled sessions %+v, want Monday and Tuesday", sessions.Data)
+	}
+	if sessions.Data[0].V != sumVolume(frames, monday, monday.Add(390*time.Minute)) {
+		t.Errorf("Monday session has volume %d", sessions.Data[0].V)
+	}
+	complete, err := hd.ResampleSessions(schedule, RegularHours, DropPartial())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if complete.Points != 1 {
+		t.Errorf("DropPartial kept %d sessions, want only Monday", complete.Points)
+	}
+	days, err := hd.Resample(1, Day, AlignToSessions(schedule, RegularHours))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if days.Points != 2 || !days.Data[0].Time().Equal(monday) {
+		t.Errorf("resampled days %+v, want bars starting at each session open", days.Data)
+	}
+	weeks, err := hd.Resample(1, Week, AlignToSessions(schedule, RegularHours), DropPartial())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weeks.Points != 0 {
+		t.Errorf("DropPartial kept a week with sessions still to come: %+v", weeks.Data)
+	}
+}
+
+func TestResampleWeeksStartingMidWeek(t *testing.T) {
+	schedule := weekdaySchedule(t)
+	loc := schedule.Location
+	wednesday := time.Date(2020, 10, 7, 9, 30, 0, 0, loc)
+	tests := []struct {
+		unit TimeUnit
+		want time.Time
+	}{
+		{Week, time.Date(2020, 10, 5, 9, 30, 0, 0, loc)},
+		{Month, time.Date(2020, 10, 1, 9, 30, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		bars, err := ResampleFrames(minuteFrames(wednesday, 390), 1, tt.unit, AlignToSessions(schedule, RegularHours))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bars) != 1 || !bars[0].Time().Equal(tt.want) {
+			t.Errorf("1%s bars of Wednesday's frames are %+v, want one bar at %v", tt.unit, bars, tt.want)
+		}
+	}
+}
+
+func TestResampleDropPartialSingleFrame(t *testing.T) {
+	start := time.Date(2020, 10, 5, 13, 30, 0, 0, time.UTC)
+	frames := minuteFrames(start, 1)
+	if _, err := ResampleFrames(frames, 15, Min, DropPartial()); err == nil {
+		t.Error("DropPartial of a single frame of unknown size succeeded, want an error")
+	}
+	if _, err := ResampleSessionFrames(frames, weekdaySchedule(t), RegularHours, DropPartial()); err == nil {
+		t.Error("DropPartial of a single frame of unknown size succeeded for sessions, want an error")
+	}
+	bars, err := ResampleFrames(frames, 15, Min)
+	if err != nil || len(bars) != 1 {
+		t.Errorf("resampled a single frame into %+v, %v, want one bar", bars, err)
+	}
+	hd := Historical{Data: minuteFrames(start, 1), BarLength: 60}
+	complete, err := hd.Resample(15, Min, DropPartial())
+	if err != nil || complete.Points != 0 {
+		t.Errorf("DropPartial kept %+v, %v, want the partial bar dropped", complete.Data, err)
+	}
+	hd = Historical{Data: minuteFrames(start, 1), BarLength: 15 * 60}
+	complete, err = hd.Resample(15, Min, DropPartial())
+	if err != nil || complete.Points != 1 {
+		t.Errorf("DropPartial dropped a bar covered by a single 15 minute frame: %+v, %v", complete.Data, err)
+	}
+}
@@ -0,0 +1,126 @@
+package ib
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"log"
+
+	resty "github.com/go-resty/resty/v2"
+)
+
+// ScannerParameters stores the instruments, locations, scan codes and filters available to market scanners.
+type ScannerParameters struct {
+	ScanTypes []struct {
+		DisplayName string   `json:"display_name"`
+		Code        string   `json:"code"`
+		Instruments []string `json:"instruments"`
+	} `json:"scan_type_list"`
+	Instruments []struct {
+		DisplayName string   `json:"display_name"`
+		Type        string   `json:"type"`
+		Filters     []string `json:"filters"`
+	} `json:"instrument_list"`
+	Filters []struct {
+		Group       string `json:"group"`
+		DisplayName string `json:"display_name"`
+		Code        string `json:"code"`
+		Type        string `json:"type"`
+	} `json:"filter_list"`
+	Locations []ScannerLocation `json:"location_tree"`
+}
+
+// ScannerLocation stores a scanner location and the locations nested under it.
+type ScannerLocation struct {
+	DisplayName string            `json:"display_name"`
+	Type        string            `json:"type"`
+	Locations   []ScannerLocation `json:"locations"`
+}
+
+// ScannerParams retrieves the parameters available to market scanners.
+func ScannerParams() ScannerParameters {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/iserver/scanner/params")
+	if err != nil {
+		log.Panic(err)
+	}
+	params := ScannerParameters{}
+	err = json.Unmarshal(resp.Body(), &params)
+	if err != nil {
+		log.Panic(err)
+	}
+	return params
+}
+
+// ScannerFilter narrows down the results of a market scanner.
+type ScannerFilter struct {
+	Code  string      `json:"code"`
+	Value interface{} `json:"value"`
+}
+
+// Scanner stores the parameters of a market scanner.
+// Instrument, Type and Location take codes returned by ScannerParams, e.g. "STK", "TOP_PERC_GAIN" and "STK.US.MAJOR".
+type Scanner struct {
+	Instrument string          `json:"instrument"`
+	Type       string          `json:"type"`
+	Location   string          `json:"location"`
+	Filters    []ScannerFilter `json:"filter"`
+}
+
+// ScannerContract stores a single row of market scanner results.
+type ScannerContract struct {
+	ServerID              string `json:"server_id"`
+	ColumnName            string `json:"column_name"`
+	Symbol                string `json:"symbol"`
+	Conidex               string `json:"conidex"`
+	Conid                 int    `json:"con_id"`
+	AvailableChartPeriods string `json:"available_chart_periods"`
+	CompanyName           string `json:"company_name"`
+	ScanData              string `json:"scan_data"`
+	ContractDescription   string `json:"contract_description_1"`
+	ListingExchange       string `json:"listing_exchange"`
+	SecType               string `json:"sec_type"`
+}
+
+// ScannerResults stores the contracts returned from a market scanner.
+type ScannerResults struct {
+	Contracts          []ScannerContract `json:"contracts"`
+	ScanDataColumnName string            `json:"scan_data_column_name"`
+}
+
+// RunScanner runs a market scanner on the brokerage server.
+func RunScanner(scanner Scanner) ScannerResults {
+	if scanner.Filters == nil {
+		scanner.Filters = make([]ScannerFilter, 0)
+	}
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().SetBody(scanner).Post(base + "/api/iserver/scanner/run")
+	if err != nil {
+		log.Panic(err)
+	}
+	results := ScannerResults{}
+	err = json.Unmarshal(resp.Body(), &results)
+	if err != nil {
+		log.Panic(err)
+	}
+	return results
+}
+
+// Security creates a security object from a scanner result
+func (sc ScannerContract) Security(ba BrokerAccount) Security {
+	security := Security{
+		Broker: ba,
+		Conid:  sc.Conid,
+	}
+	return security
+}
+
+// Securities creates security objects from all scanner results
+func (sr ScannerResults) Securities(ba BrokerAccount) []Security {
+	securities := make([]Security, 0)
+	for _, c := range sr.Contracts {
+		securities = append(securities, c.Security(ba))
+	}
+	return securities
+}
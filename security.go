@@ -1,5 +1,15 @@
 package ib
 
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	resty "github.com/go-resty/resty/v2"
+)
+
 // Security stores information about a security
 type Security struct {
 	Broker BrokerAccount
@@ -14,3 +24,53 @@ func (ba BrokerAccount) Security(p Position) Security {
 	}
 	return security
 }
+
+// ContractInfo stores contract details for a security
+type ContractInfo struct {
+	Conid             int    `json:"con_id"`
+	Symbol            string `json:"symbol"`
+	LocalSymbol       string `json:"local_symbol"`
+	CompanyName       string `json:"company_name"`
+	InstrumentType    string `json:"instrument_type"`
+	Exchange          string `json:"exchange"`
+	ValidExchanges    string `json:"valid_exchanges"`
+	Currency          string `json:"currency"`
+	TradingClass      string `json:"trading_class"`
+	Multiplier        string `json:"multiplier"`
+	ExpiryFull        string `json:"expiry_full"`
+	MaturityDate      string `json:"maturity_date"`
+	ContractMonth     string `json:"contract_month"`
+	UnderlyingConid   int    `json:"underlying_con_id"`
+	Industry          string `json:"industry"`
+	Category          string `json:"category"`
+	RegularTradingHrs bool   `json:"r_t_h"`
+	SmartAvailable    bool   `json:"smart_available"`
+	Text              string `json:"text"`
+}
+
+// Info retrieves the contract details of a security
+func (s Security) Info() ContractInfo {
+	info, err := s.info()
+	if err != nil {
+		log.Panic(err)
+	}
+	return info
+}
+
+func (s Security) info() (ContractInfo, error) {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/iserver/contract/" + strconv.Itoa(s.Conid) + "/info")
+	if err != nil {
+		return ContractInfo{}, err
+	}
+	if resp.IsError() {
+		return ContractInfo{}, fmt.Errorf("ib: contract info for %d: %s", s.Conid, resp.Status())
+	}
+	info := ContractInfo{}
+	err = json.Unmarshal(resp.Body(), &info)
+	if err != nil {
+		return ContractInfo{}, err
+	}
+	return info, nil
+}
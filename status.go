@@ -0,0 +1,162 @@
+package ib
+
+import "fmt"
+
+// DataKind represents whether market data is real time, delayed or frozen.
+type DataKind int
+
+const (
+	// UnknownData is market data without availability information.
+	UnknownData DataKind = iota
+	// RealTime market data is streamed as it happens.
+	RealTime
+	// Delayed market data lags behind, usually by 15 to 20 minutes, as the account lacks a subscription.
+	Delayed
+	// Frozen market data is the last value recorded when the market closed.
+	Frozen
+	// FrozenDelayed market data is the last delayed value recorded when the market closed.
+	FrozenDelayed
+	// NoPermission means the account isn't subscribed to market data for the security.
+	NoPermission
+)
+
+// String returns the name of the data kind.
+func (dk DataKind) String() string {
+	switch dk {
+	case RealTime:
+		return "RealTime"
+	case Delayed:
+		return "Delayed"
+	case Frozen:
+		return "Frozen"
+	case FrozenDelayed:
+		return "FrozenDelayed"
+	case NoPermission:
+		return "NoPermission"
+	}
+	return "Unknown"
+}
+
+// MarketDataStatus stores the quality of market data.
+type MarketDataStatus struct {
+	Kind DataKind
+	// DelayMinutes is how far delayed data lags behind, if known.
+	DelayMinutes int
+	// Snapshot is set for data taken as a snapshot rather than streamed.
+	Snapshot bool
+	// Consolidated is set for data consolidated across exchanges.
+	Consolidated bool
+	// Book is set for data including the top of the order book.
+	Book bool
+}
+
+// ParseMarketDataStatus decodes a market data availability code, e.g. "RpB", and a delay in minutes.
+// The first letter is R for real time, D for delayed, Z for frozen, Y for frozen delayed and N for no permission,
+// followed by P for snapshot data, p for consolidated data and B for top of book data.
+// Data with a delay is delayed whatever the code.
+func ParseMarketDataStatus(code string, delayMinutes int) MarketDataStatus {
+	mds := MarketDataStatus{
+		DelayMinutes: delayMinutes,
+	}
+	if code == "" {
+		mds.applyDelay()
+		return mds
+	}
+	switch code[0] {
+	case 'R':
+		mds.Kind = RealTime
+	case 'D':
+		mds.Kind = Delayed
+	case 'Z':
+		mds.Kind = Frozen
+	case 'Y':
+		mds.Kind = FrozenDelayed
+	case 'N':
+		mds.Kind = NoPermission
+	}
+	for _, c := range code[1:] {
+		switch c {
+		case 'P':
+			mds.Snapshot = true
+		case 'p':
+			mds.Consolidated = true
+		case 'B':
+			mds.Book = true
+		}
+	}
+	mds.applyDelay()
+	return mds
+}
+
+// applyDelay marks data with a delay as delayed.
+func (mds *MarketDataStatus) applyDelay() {
+	if mds.DelayMinutes <= 0 {
+		return
+	}
+	switch mds.Kind {
+	case Frozen, FrozenDelayed:
+		mds.Kind = FrozenDelayed
+	case NoPermission:
+	default:
+		mds.Kind = Delayed
+	}
+}
+
+// IsRealTime reports whether the market data is real time.
+func (mds MarketDataStatus) IsRealTime() bool {
+	return mds.Kind == RealTime
+}
+
+// IsDelayed reports whether the market data is delayed.
+func (mds MarketDataStatus) IsDelayed() bool {
+	return mds.Kind == Delayed || mds.Kind == FrozenDelayed
+}
+
+// Status returns the quality of the snapshots market data.
+// MarketDataAvailability must be requested for the status to be known.
+func (s Snapshot) Status() MarketDataStatus {
+	return ParseMarketDataStatus(s.MarketDataAvailability, 0)
+}
+
+// ParseHistoricalStatus decodes the market data availability code and delay in minutes of historical data.
+// The history endpoint uses its own single letter codes: S and R for real time, D for delayed,
+// Z for frozen, Y for frozen delayed and N for no permission. Data with a delay is delayed whatever the code.
+func ParseHistoricalStatus(code string, delayMinutes int) MarketDataStatus {
+	mds := MarketDataStatus{
+		DelayMinutes: delayMinutes,
+	}
+	if code != "" {
+		switch code[0] {
+		case 'S', 'R':
+			mds.Kind = RealTime
+		case 'D':
+			mds.Kind = Delayed
+		case 'Z':
+			mds.Kind = Frozen
+		case 'Y':
+			mds.Kind = FrozenDelayed
+		case 'N':
+			mds.Kind = NoPermission
+		}
+	}
+	mds.applyDelay()
+	return mds
+}
+
+// Status returns the quality of the historical market data.
+func (hd Historical) Status() MarketDataStatus {
+	return ParseHistoricalStatus(hd.MdAvailability, hd.MktDataDelay)
+}
+
+// RequireRealTime makes snapshots and historical data fail when their market data isn't real time,
+// so strategies don't trade on delayed prices. Snapshots always request MarketDataAvailability while it is set.
+// Calls returning an error return it, Snapshot, BatchSnapshot and Security.Historical panic.
+var RequireRealTime = false
+
+// checkRealTime returns an error if real time data is required and the status isn't real time.
+func checkRealTime(conid int, mds MarketDataStatus) error {
+	if RequireRealTime && !mds.IsRealTime() {
+		return fmt.Errorf("ib: market data for %d is %s, real time data is required", conid, mds.Kind)
+	}
+	return nil
+}
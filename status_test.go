@@ -0,0 +1,44 @@
+package ib
+
+import "testing"
+
+func TestMarketDataStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		got  MarketDataStatus
+		kind DataKind
+	}{
+		{"snapshot real time", ParseMarketDataStatus("RpB", 0), RealTime},
+		{"snapshot delayed", ParseMarketDataStatus("DpB", 0), Delayed},
+		{"snapshot frozen", ParseMarketDataStatus("Z", 0), Frozen},
+		{"snapshot no permission", ParseMarketDataStatus("N", 0), NoPermission},
+		{"snapshot unknown", ParseMarketDataStatus("", 0), UnknownData},
+		{"snapshot delay only", ParseMarketDataStatus("", 15), Delayed},
+		{"snapshot frozen with delay", ParseMarketDataStatus("Z", 15), FrozenDelayed},
+		{"history streaming", ParseHistoricalStatus("S", 0), RealTime},
+		{"history real time", ParseHistoricalStatus("R", 0), RealTime},
+		{"history delay only", ParseHistoricalStatus("", 15), Delayed},
+		{"history streaming with delay", ParseHistoricalStatus("S", 15), Delayed},
+		{"history delayed", ParseHistoricalStatus("D", 0), Delayed},
+	}
+	for _, tt := range tests {
+		if tt.got.Kind != tt.kind {
+			t.Errorf("%s: kind = %s, want %s", tt.name, tt.got.Kind, tt.kind)
+		}
+	}
+}
+
+func TestCheckRealTime(t *testing.T) {
+	defer func(require bool) { RequireRealTime = require }(RequireRealTime)
+	RequireRealTime = true
+	if err := checkRealTime(1, ParseHistoricalStatus("S", 0)); err != nil {
+		t.Errorf("real time data failed the check: %v", err)
+	}
+	if err := checkRealTime(1, ParseHistoricalStatus("S", 15)); err == nil {
+		t.Error("delayed data passed the check")
+	}
+	RequireRealTime = false
+	if err := checkRealTime(1, ParseHistoricalStatus("D", 15)); err != nil {
+		t.Errorf("delayed data failed the check without RequireRealTime: %v", err)
+	}
+}
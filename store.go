@@ -0,0 +1,539 @@
+package ib
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// BarStore is a file based store of historical bars keyed by contract id and bar size.
+// Bars are appended to a JSON Lines file per key, which is rewritten in order when compacted.
+type BarStore struct {
+	mu        sync.Mutex
+	dir       string
+	schedules map[int]cachedSchedule
+}
+
+// storeScheduleTTL is how long a bar store caches the trading schedule of a security.
+const storeScheduleTTL = 12 * time.Hour
+
+type cachedSchedule struct {
+	schedule TradingSchedule
+	expires  time.Time
+}
+
+// NewBarStore creates a bar store in dir, creating the directory if needed.
+func NewBarStore(dir string) (*BarStore, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &BarStore{dir: dir, schedules: make(map[int]cachedSchedule)}, nil
+}
+
+// HistoricalStore is read through by Security.Historical when set,
+// so only bars newer than the last stored bar are requested.
+var HistoricalStore *BarStore
+
+// storeMeta is the metadata stored alongside the bars of a contract id and bar size.
+type storeMeta struct {
+	Historical
+	// CoveredFrom and CoveredTo are the range downloaded into the store without interruption, in epoch milliseconds.
+	CoveredFrom int64 `json:"coveredFrom,omitempty"`
+	CoveredTo   int64 `json:"coveredTo,omitempty"`
+	// Empty holds the opens of gaps which were repaired but hold no bars, in epoch milliseconds.
+	Empty []int64 `json:"empty,omitempty"`
+}
+
+// covers reports whether the downloaded range reaches back to t.
+func (sm storeMeta) covers(t time.Time) bool {
+	return sm.CoveredFrom != 0 && sm.CoveredFrom <= toMillis(t)
+}
+
+// extend adds a downloaded range to the covered range, replacing it if they don't overlap.
+func (sm *storeMeta) extend(from time.Time, to time.Time) {
+	f, t := toMillis(from), toMillis(to)
+	if sm.CoveredFrom == 0 || f > sm.CoveredTo || t < sm.CoveredFrom {
+		sm.CoveredFrom, sm.CoveredTo = f, t
+		return
+	}
+	if f < sm.CoveredFrom {
+		sm.CoveredFrom = f
+	}
+	if t > sm.CoveredTo {
+		sm.CoveredTo = t
+	}
+}
+
+// toMillis converts a time to epoch milliseconds.
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// key returns the file name prefix of a contract id and bar size.
+func (bs *BarStore) key(conid int, barSize int, barUnit TimeUnit) string {
+	return filepath.Join(bs.dir, strconv.Itoa(conid)+"_"+strconv.Itoa(barSize)+string(barUnit))
+}
+
+// Load reads the stored bars of a contract id and bar size in time order.
+// A Historical without data is returned if nothing is stored.
+func (bs *BarStore) Load(conid int, barSize int, barUnit TimeUnit) (Historical, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	historical, _, err := bs.load(conid, barSize, barUnit)
+	return historical, err
+}
+
+func (bs *BarStore) load(conid int, barSize int, barUnit TimeUnit) (Historical, storeMeta, error) {
+	key := bs.key(conid, barSize, barUnit)
+	meta := storeMeta{}
+	body, err := ioutil.ReadFile(key + ".json")
+	if err != nil && !os.IsNotExist(err) {
+		return Historical{}, meta, err
+	}
+	if err == nil {
+		err = json.Unmarshal(body, &meta)
+		if err != nil {
+			return Historical{}, meta, err
+		}
+	}
+	historical := meta.Historical
+	f, err := os.Open(key + ".jsonl")
+	if os.IsNotExist(err) {
+		return historical, meta, nil
+	}
+	if err != nil {
+		return historical, meta, err
+	}
+	defer f.Close()
+	bars := make(map[int64]HistoricalDataFrame)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		frame := HistoricalDataFrame{}
+		err := json.Unmarshal(scanner.Bytes(), &frame)
+		if err != nil {
+			// A partially written line from an interrupted append, compaction drops it.
+			continue
+		}
+		bars[frame.T] = frame
+	}
+	if err := scanner.Err(); err != nil {
+		return historical, meta, err
+	}
+	historical.Data = sortedFrames(bars)
+	historical.Points = len(historical.Data)
+	return historical, meta, nil
+}
+
+// sortedFrames returns frames in time order.
+func sortedFrames(bars map[int64]HistoricalDataFrame) []HistoricalDataFrame {
+	frames := make([]HistoricalDataFrame, 0, len(bars))
+	for _, f := range bars {
+		frames = append(frames, f)
+	}
+	sort.Slice(frames, func(i, j int) bool {
+		return frames[i].T < frames[j].T
+	})
+	return frames
+}
+
+// Save appends bars to the store and replaces the stored metadata, keeping the covered range.
+// Bars already stored are replaced by the newer copy when loaded.
+func (bs *BarStore) Save(conid int, barSize int, barUnit TimeUnit, hd Historical) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	_, meta, err := bs.load(conid, barSize, barUnit)
+	if err != nil {
+		return err
+	}
+	meta.Historical = hd
+	err = bs.writeMeta(conid, barSize, barUnit, meta)
+	if err != nil {
+		return err
+	}
+	return bs.appendFrames(conid, barSize, barUnit, hd.Data)
+}
+
+// writeMeta replaces the stored metadata.
+func (bs *BarStore) writeMeta(conid int, barSize int, barUnit TimeUnit, meta storeMeta) error {
+	meta.Data = nil
+	meta.Points = 0
+	body, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(bs.key(conid, barSize, barUnit)+".json", body, 0644)
+}
+
+// appendFrames appends bars to the stored bars.
+func (bs *BarStore) appendFrames(conid int, barSize int, barUnit TimeUnit, frames []HistoricalDataFrame) error {
+	if len(frames) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(bs.key(conid, barSize, barUnit)+".jsonl", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, frame := range frames {
+		line, err := json.Marshal(frame)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	err = w.Flush()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Compact rewrites the stored bars of a contract id and bar size in time order without duplicates.
+func (bs *BarStore) Compact(conid int, barSize int, barUnit TimeUnit) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	hd, _, err := bs.load(conid, barSize, barUnit)
+	if err != nil {
+		return err
+	}
+	key := bs.key(conid, barSize, barUnit)
+	f, err := ioutil.TempFile(bs.dir, filepath.Base(key)+".tmp")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, frame := range hd.Data {
+		line, err := json.Marshal(frame)
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return err
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	err = w.Flush()
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), key+".jsonl")
+}
+
+// schedule returns the trading schedule of a security, retrieving it if it isn't cached.
+func (bs *BarStore) schedule(s Security, now time.Time) (TradingSchedule, error) {
+	bs.mu.Lock()
+	cached, ok := bs.schedules[s.Conid]
+	bs.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.schedule, nil
+	}
+	schedule, err := s.TradingSchedule()
+	if err != nil {
+		return TradingSchedule{}, err
+	}
+	bs.mu.Lock()
+	bs.schedules[s.Conid] = cachedSchedule{schedule: schedule, expires: now.Add(storeScheduleTTL)}
+	bs.mu.Unlock()
+	return schedule, nil
+}
+
+// tradingPeriodStart returns the start of the period of n units leading up to now as the brokerage server counts it.
+// Days are counted in trading sessions and minutes and hours in trading time,
+// longer periods in calendar time from the first session closing after their start.
+func tradingPeriodStart(schedule TradingSchedule, now time.Time, n int, unit TimeUnit, hours Hours) time.Time {
+	from := periodStart(now, n, unit)
+	lookback := from.AddDate(0, 0, -maxScheduleLookahead)
+	if unit == Day {
+		lookback = now.AddDate(0, 0, -2*n-maxScheduleLookahead)
+	}
+	started := make([]TradingSession, 0)
+	for _, session := range schedule.Sessions(lookback, now.AddDate(0, 0, 1), hours) {
+		if session.Open.Before(now) {
+			started = append(started, session)
+		}
+	}
+	switch unit {
+	case Day:
+		if len(started) >= n {
+			return started[len(started)-n].Open
+		}
+	case Min, Hour:
+		remaining := approxDuration(n, unit)
+		for i := len(started) - 1; i >= 0; i-- {
+			end := started[i].Close
+			if end.After(now) {
+				end = now
+			}
+			if d := end.Sub(started[i].Open); d < remaining {
+				remaining -= d
+				continue
+			}
+			return end.Add(-remaining)
+		}
+	default:
+		for _, session := range started {
+			if session.Close.After(from) {
+				if session.Open.After(from) {
+					return session.Open
+				}
+				return from
+			}
+		}
+	}
+	return from
+}
+
+// Update brings the stored bars of a security up to date and returns the bars of the last period,
+// the same bars the brokerage server returns for the period.
+// Only bars from the last stored bar on are requested, unless the range downloaded before doesn't reach back to the start of the period.
+// The start of the period is worked out from the trading schedule of the security,
+// if the schedule isn't available the whole period is requested instead.
+// The store isn't locked while downloading.
+func (bs *BarStore) Update(s Security, period int, unit TimeUnit, barSize int, barUnit TimeUnit) (Historical, error) {
+	return bs.update(s, period, unit, barSize, barUnit, time.Now())
+}
+
+func (bs *BarStore) update(s Security, period int, unit TimeUnit, barSize int, barUnit TimeUnit, now time.Time) (Historical, error) {
+	err := s.History(period, unit, barSize, barUnit).Validate()
+	if err != nil {
+		return Historical{}, err
+	}
+	bs.mu.Lock()
+	stored, meta, err := bs.load(s.Conid, barSize, barUnit)
+	bs.mu.Unlock()
+	if err != nil {
+		return Historical{}, err
+	}
+	bar := approxDuration(barSize, barUnit)
+	var start, from time.Time
+	var fetched Historical
+	schedule, err := bs.schedule(s, now)
+	if err == nil {
+		start = tradingPeriodStart(schedule, now, period, unit, RegularHours)
+		// Daily and longer bars are stamped before the session they belong to.
+		from = start.Add(-bar)
+		if meta.covers(from) {
+			from = time.Unix(0, meta.CoveredTo*int64(time.Millisecond))
+			// The last stored bar may have been incomplete when it was downloaded.
+			if n := len(stored.Data); n > 0 && stored.Data[n-1].Time().Before(from) {
+				from = stored.Data[n-1].Time()
+			}
+		}
+		fetched, err = s.Download(from, now, barSize, barUnit).Fetch()
+	} else {
+		fetched, err = s.History(period, unit, barSize, barUnit).Fetch()
+		start, from = now, now
+		if len(fetched.Data) > 0 {
+			start, from = fetched.Data[0].Time(), fetched.Data[0].Time()
+		}
+	}
+	if err != nil {
+		return Historical{}, err
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	// Reload, as another update may have saved bars while downloading.
+	stored, meta, err = bs.load(s.Conid, barSize, barUnit)
+	if err != nil {
+		return Historical{}, err
+	}
+	storedBars := make(map[int64]HistoricalDataFrame)
+	for _, f := range stored.Data {
+		storedBars[f.T] = f
+	}
+	changed := make([]HistoricalDataFrame, 0)
+	for _, f := range fetched.Data {
+		if old, ok := storedBars[f.T]; !ok || old != f {
+			changed = append(changed, f)
+		}
+	}
+	meta.Historical = fetched
+	meta.extend(from, now)
+	err = bs.writeMeta(s.Conid, barSize, barUnit, meta)
+	if err != nil {
+		return Historical{}, err
+	}
+	err = bs.appendFrames(s.Conid, barSize, barUnit, changed)
+	if err != nil {
+		return Historical{}, err
+	}
+	bars := make(map[int64]HistoricalDataFrame)
+	for _, frames := range [][]HistoricalDataFrame{stored.Data, fetched.Data} {
+		for _, f := range frames {
+			if f.Time().Add(bar).After(start) {
+				bars[f.T] = f
+			}
+		}
+	}
+	fetched.Data = sortedFrames(bars)
+	fetched.Points = len(fetched.Data)
+	return fetched, nil
+}
+
+// periodKey returns the date of the start of the day, week or month t falls in.
+func periodKey(t time.Time, unit TimeUnit) string {
+	return calendarStart(t, unit).Format("20060102")
+}
+
+// barPeriodKeys returns the periods a daily or longer bar may belong to.
+// Bars are stamped at midnight in either UTC or the exchange time zone, so both dates are considered.
+func barPeriodKeys(f HistoricalDataFrame, unit TimeUnit, loc *time.Location) []string {
+	return []string{periodKey(f.Time().UTC(), unit), periodKey(f.Time().In(loc), unit)}
+}
+
+// periodSessions groups the sessions of a schedule by the day, week or month they close in,
+// returning one session from the first open to the last close of each period, keyed by period.
+func periodSessions(sessions []TradingSession, unit TimeUnit, loc *time.Location) ([]string, map[string]TradingSession) {
+	keys := make([]string, 0)
+	periods := make(map[string]TradingSession)
+	for _, session := range sessions {
+		k := periodKey(session.Close.In(loc), unit)
+		p, ok := periods[k]
+		if !ok {
+			keys = append(keys, k)
+			periods[k] = session
+			continue
+		}
+		if session.Open.Before(p.Open) {
+			p.Open = session.Open
+		}
+		if session.Close.After(p.Close) {
+			p.Close = session.Close
+		}
+		periods[k] = p
+	}
+	return keys, periods
+}
+
+// Gaps returns the regular trading sessions between the first and last stored bar of a security which hold no bars.
+// For daily, weekly and monthly bars a gap is a day, week or month with sessions but without a bar,
+// returned as a session from its first open to its last close.
+// Gaps which were repaired before but hold no bars, e.g. unscheduled holidays, are not returned again.
+func (bs *BarStore) Gaps(s Security, barSize int, barUnit TimeUnit, schedule TradingSchedule) ([]TradingSession, error) {
+	bs.mu.Lock()
+	stored, meta, err := bs.load(s.Conid, barSize, barUnit)
+	bs.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	gaps := make([]TradingSession, 0)
+	if len(stored.Data) < 2 || schedule.Location == nil {
+		return gaps, nil
+	}
+	empty := make(map[int64]bool)
+	for _, open := range meta.Empty {
+		empty[open] = true
+	}
+	first := stored.Data[0].Time()
+	last := stored.Data[len(stored.Data)-1].Time()
+	if barUnit != Min && barUnit != Hour {
+		covered := make(map[string]bool)
+		for _, f := range stored.Data {
+			for _, k := range barPeriodKeys(f, barUnit, schedule.Location) {
+				covered[k] = true
+			}
+		}
+		firstKeys := barPeriodKeys(stored.Data[0], barUnit, schedule.Location)
+		lastKeys := barPeriodKeys(stored.Data[len(stored.Data)-1], barUnit, schedule.Location)
+		margin := approxDuration(1, barUnit)
+		keys, periods := periodSessions(schedule.Sessions(first.Add(-margin), last.Add(margin), RegularHours), barUnit, schedule.Location)
+		for _, k := range keys {
+			if k < firstKeys[0] && k < firstKeys[1] || k > lastKeys[0] && k > lastKeys[1] {
+				continue
+			}
+			if !covered[k] && !empty[toMillis(periods[k].Open)] {
+				gaps = append(gaps, periods[k])
+			}
+		}
+		return gaps, nil
+	}
+	i := 0
+	for _, session := range schedule.Sessions(first, last, RegularHours) {
+		if session.Open.Before(first) || session.Close.After(last) {
+			continue
+		}
+		for i < len(stored.Data) && stored.Data[i].Time().Before(session.Open) {
+			i++
+		}
+		if (i == len(stored.Data) || !stored.Data[i].Time().Before(session.Close)) && !empty[toMillis(session.Open)] {
+			gaps = append(gaps, session)
+		}
+	}
+	return gaps, nil
+}
+
+// fills reports whether any of the frames belong to a gap.
+func fills(frames []HistoricalDataFrame, gap TradingSession, barUnit TimeUnit, loc *time.Location) bool {
+	for _, f := range frames {
+		if barUnit == Min || barUnit == Hour {
+			if gap.Contains(f.Time()) {
+				return true
+			}
+			continue
+		}
+		for _, k := range barPeriodKeys(f, barUnit, loc) {
+			if k == periodKey(gap.Close.In(loc), barUnit) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Repair downloads the bars of every gap in the stored bars of a security and compacts the store.
+// Gaps for which the brokerage server returns no bars are remembered and not repaired again.
+// It returns the number of gaps downloaded.
+func (bs *BarStore) Repair(s Security, barSize int, barUnit TimeUnit) (int, error) {
+	schedule, err := s.TradingSchedule()
+	if err != nil {
+		return 0, err
+	}
+	gaps, err := bs.Gaps(s, barSize, barUnit, schedule)
+	if err != nil {
+		return 0, err
+	}
+	repaired := 0
+	for _, gap := range gaps {
+		from, to := gap.Open, gap.Close
+		if barUnit != Min && barUnit != Hour {
+			// Daily and longer bars are stamped outside the session, download around it.
+			margin := approxDuration(1, barUnit)
+			from, to = from.Add(-margin), to.Add(margin)
+		}
+		fetched, err := s.Download(from, to, barSize, barUnit).Fetch()
+		if err != nil {
+			return repaired, err
+		}
+		bs.mu.Lock()
+		_, meta, err := bs.load(s.Conid, barSize, barUnit)
+		if err == nil {
+			err = bs.appendFrames(s.Conid, barSize, barUnit, fetched.Data)
+		}
+		if err == nil && !fills(fetched.Data, gap, barUnit, schedule.Location) {
+			meta.Empty = append(meta.Empty, toMillis(gap.Open))
+			err = bs.writeMeta(s.Conid, barSize, barUnit, meta)
+		}
+		bs.mu.Unlock()
+		if err != nil {
+			return repaired, err
+		}
+		repaired++
+	}
+	return repaired, bs.Compact(s.Conid, barSize, barUnit)
+}
@@ -0,0 +1,363 @@
+package ib
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *BarStore {
+	dir, err := ioutil.TempDir("", "barstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	bs, err := NewBarStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bs
+}
+
+func countLines(t *testing.T, path string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	n := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		n++
+	}
+	return n
+}
+
+func frameAt(t time.Time, price float64) HistoricalDataFrame {
+	return HistoricalDataFrame{T: toMillis(t), O: price, H: price + 1, L: price - 1, C: price, V: 100}
+}
+
+// fakeGateway serves a security trading from 09:30 to 16:00 New York time on weekdays and its bars
+// like the brokerage server, counting day periods in trading sessions up to now or the requested start time.
+type fakeGateway struct {
+	mu      sync.Mutex
+	now     time.Time
+	bars    []HistoricalDataFrame
+	periods []string
+}
+
+func (g *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/info"):
+		json.NewEncoder(w).Encode(ContractInfo{Conid: 1, Symbol: "TEST", Exchange: "NASDAQ", InstrumentType: "STK"})
+	case strings.HasSuffix(r.URL.Path, "/schedule"):
+		days := make([]tradingDay, 0)
+		for d := 3; d <= 7; d++ {
+			days = append(days, tradingDay{
+				TradingScheduleDate: fmt.Sprintf("200001%02d", d),
+				Sessions:            []tradingHours{{OpeningTime: "0930", ClosingTime: "1600"}},
+			})
+		}
+		json.NewEncoder(w).Encode([]tradingScheduleResponse{{Schedules: days}})
+	case strings.HasSuffix(r.URL.Path, "/history"):
+		period := r.URL.Query().Get("period")
+		g.periods = append(g.periods, period)
+		end := g.now
+		if st := r.URL.Query().Get("startTime"); st != "" {
+			end, _ = time.Parse("20060102-15:04:05", st)
+		}
+		loc := g.now.Location()
+		var start time.Time
+		if n, err := strconv.Atoi(strings.TrimSuffix(period, "d")); err == nil {
+			day := time.Date(end.In(loc).Year(), end.In(loc).Month(), end.In(loc).Day(), 9, 30, 0, 0, loc)
+			for ; n > 0; day = day.AddDate(0, 0, -1) {
+				if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday && day.Before(end) {
+					start = day
+					n--
+				}
+			}
+		} else if d, err := time.ParseDuration(strings.TrimSuffix(period, "in")); err == nil {
+			start = end.Add(-d)
+		}
+		data := make([]HistoricalDataFrame, 0)
+		for _, f := range g.bars {
+			if !f.Time().Before(start) && !f.Time().After(end) {
+				data = append(data, f)
+			}
+		}
+		json.NewEncoder(w).Encode(Historical{Symbol: "TEST", MdAvailability: "S", Data: data})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// serve points the client at the gateway for the duration of a test.
+func (g *fakeGateway) serve(t *testing.T) {
+	server := httptest.NewTLSServer(g)
+	b := base
+	base = server.URL
+	t.Cleanup(func() {
+		base = b
+		server.Close()
+	})
+}
+
+// hourlyBars returns a bar every hour from 09:30 to 15:30 on every weekday between from and to.
+func hourlyBars(from time.Time, to time.Time) []HistoricalDataFrame {
+	bars := make([]HistoricalDataFrame, 0)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+		for h := 9; h < 16; h++ {
+			open := time.Date(day.Year(), day.Month(), day.Day(), h, 30, 0, 0, day.Location())
+			bars = append(bars, frameAt(open, float64(day.Day()*100+h)))
+		}
+	}
+	return bars
+}
+
+func TestBarStoreUpdateOnlyDownloadsNewBars(t *testing.T) {
+	loc := weekdaySchedule(t).Location
+	now := time.Date(2020, 10, 13, 12, 0, 0, 0, loc)
+	g := &fakeGateway{now: now, bars: hourlyBars(time.Date(2020, 10, 1, 0, 0, 0, 0, loc), now)}
+	g.serve(t)
+
+	bs := newTestStore(t)
+	s := Security{Conid: 1}
+	first, err := bs.update(s, 1, Day, 1, Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Points != 3 {
+		t.Fatalf("first update returned %d bars, want 3", first.Points)
+	}
+	firstRequests := len(g.periods)
+	second, err := bs.update(s, 1, Day, 1, Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Points != 3 {
+		t.Errorf("second update returned %d bars, want 3", second.Points)
+	}
+	if len(g.periods) != firstRequests+1 {
+		t.Fatalf("second update made %d requests, want 1", len(g.periods)-firstRequests)
+	}
+	if p := g.periods[len(g.periods)-1]; p != "1h" {
+		t.Errorf("second update requested a period of %s, want only the bars since the last stored bar", p)
+	}
+	if n := countLines(t, bs.key(1, 1, Hour)+".jsonl"); n != 3 {
+		t.Errorf("store holds %d lines after two updates, want 3", n)
+	}
+}
+
+func TestBarStoreUpdateMatchesHistory(t *testing.T) {
+	loc := weekdaySchedule(t).Location
+	tests := []struct {
+		name   string
+		now    time.Time
+		period int
+	}{
+		{"monday morning", time.Date(2020, 10, 12, 10, 0, 0, 0, loc), 2},
+		{"monday before the open", time.Date(2020, 10, 12, 8, 0, 0, 0, loc), 2},
+		{"saturday", time.Date(2020, 10, 10, 12, 0, 0, 0, loc), 1},
+		{"wednesday", time.Date(2020, 10, 14, 15, 0, 0, 0, loc), 5},
+	}
+	for _, tt := range tests {
+		g := &fakeGateway{now: tt.now, bars: hourlyBars(time.Date(2020, 10, 1, 0, 0, 0, 0, loc), tt.now)}
+		g.serve(t)
+		s := Security{Conid: 1}
+		want, err := s.History(tt.period, Day, 1, Hour).Fetch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bs := newTestStore(t)
+		got, err := bs.update(s, tt.period, Day, 1, Hour, tt.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := bs.schedules[s.Conid]; !ok {
+			t.Fatalf("%s: the store didn't use the trading schedule", tt.name)
+		}
+		if len(got.Data) != len(want.Data) {
+			t.Errorf("%s: store returned %d bars, the brokerage server %d", tt.name, len(got.Data), len(want.Data))
+			continue
+		}
+		for i := range want.Data {
+			if got.Data[i] != want.Data[i] {
+				t.Errorf("%s: bar %d is %v from the store, %v from the brokerage server", tt.name, i, got.Data[i].Time(), want.Data[i].Time())
+			}
+		}
+	}
+}
+
+func TestBarStoreCompact(t *testing.T) {
+	bs := newTestStore(t)
+	start := time.Date(2020, 10, 5, 13, 30, 0, 0, time.UTC)
+	hd := Historical{Data: []HistoricalDataFrame{frameAt(start.Add(time.Minute), 2), frameAt(start, 1)}}
+	for i := 0; i < 2; i++ {
+		if err := bs.Save(1, 1, Min, hd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := bs.key(1, 1, Min) + ".jsonl"
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"o":1,"c":`)
+	f.Close()
+	if err := bs.Compact(1, 1, Min); err != nil {
+		t.Fatal(err)
+	}
+	if n := countLines(t, path); n != 2 {
+		t.Errorf("compacted store holds %d lines, want 2", n)
+	}
+	loaded, err := bs.Load(1, 1, Min)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Points != 2 || loaded.Data[0].T > loaded.Data[1].T {
+		t.Errorf("loaded %+v, want 2 bars in time order", loaded.Data)
+	}
+	matches, _ := filepath.Glob(filepath.Join(bs.dir, "*.tmp*"))
+	if len(matches) > 0 {
+		t.Errorf("compaction left temporary files %v", matches)
+	}
+}
+
+func TestBarStoreGaps(t *testing.T) {
+	schedule := weekdaySchedule(t)
+	loc := schedule.Location
+	day := func(d int, h int, m int) time.Time {
+		return time.Date(2020, 10, d, h, m, 0, 0, loc)
+	}
+	tests := []struct {
+		name    string
+		barSize int
+		barUnit TimeUnit
+		frames  []HistoricalDataFrame
+		gaps    []time.Time
+	}{
+		{
+			name:    "intraday missing wednesday",
+			barSize: 1, barUnit: Hour,
+			frames: []HistoricalDataFrame{
+				frameAt(day(5, 9, 30), 1), frameAt(day(5, 15, 30), 1),
+				frameAt(day(6, 9, 30), 1), frameAt(day(6, 15, 30), 1),
+				frameAt(day(8, 9, 30), 1), frameAt(day(8, 15, 30), 1),
+			},
+			gaps: []time.Time{day(7, 9, 30)},
+		},
+		{
+			name:    "daily stamped at exchange midnight",
+			barSize: 1, barUnit: Day,
+			frames: []HistoricalDataFrame{
+				frameAt(day(5, 0, 0), 1), frameAt(day(6, 0, 0), 1), frameAt(day(8, 0, 0), 1),
+				frameAt(day(9, 0, 0), 1), frameAt(day(12, 0, 0), 1), frameAt(day(13, 0, 0), 1),
+			},
+			gaps: []time.Time{day(7, 9, 30)},
+		},
+		{
+			name:    "daily stamped at UTC midnight",
+			barSize: 1, barUnit: Day,
+			frames: []HistoricalDataFrame{
+				frameAt(time.Date(2020, 10, 5, 0, 0, 0, 0, time.UTC), 1),
+				frameAt(time.Date(2020, 10, 6, 0, 0, 0, 0, time.UTC), 1),
+				frameAt(time.Date(2020, 10, 7, 0, 0, 0, 0, time.UTC), 1),
+				frameAt(time.Date(2020, 10, 8, 0, 0, 0, 0, time.UTC), 1),
+				frameAt(time.Date(2020, 10, 9, 0, 0, 0, 0, time.UTC), 1),
+			},
+		},
+		{
+			name:    "weekly",
+			barSize: 1, barUnit: Week,
+			frames: []HistoricalDataFrame{
+				frameAt(day(5, 0, 0), 1), frameAt(day(12, 0, 0), 1),
+				frameAt(day(19, 0, 0), 1), frameAt(day(26, 0, 0), 1),
+			},
+		},
+	}
+	for _, tt := range tests {
+		bs := newTestStore(t)
+		if err := bs.Save(1, tt.barSize, tt.barUnit, Historical{Data: tt.frames}); err != nil {
+			t.Fatal(err)
+		}
+		gaps, err := bs.Gaps(Security{Conid: 1}, tt.barSize, tt.barUnit, schedule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(gaps) != len(tt.gaps) {
+			t.Errorf("%s: found gaps %v, want %v", tt.name, gaps, tt.gaps)
+			continue
+		}
+		for i, gap := range gaps {
+			if !gap.Open.Equal(tt.gaps[i]) {
+				t.Errorf("%s: gap %d opens at %v, want %v", tt.name, i, gap.Open, tt.gaps[i])
+			}
+		}
+	}
+}
+
+func TestBarStoreGapsSkipsEmptyRepairs(t *testing.T) {
+	schedule := weekdaySchedule(t)
+	loc := schedule.Location
+	bs := newTestStore(t)
+	frames := []HistoricalDataFrame{
+		frameAt(time.Date(2020, 10, 5, 9, 30, 0, 0, loc), 1),
+		frameAt(time.Date(2020, 10, 7, 9, 30, 0, 0, loc), 1),
+	}
+	if err := bs.Save(1, 1, Hour, Historical{Data: frames}); err != nil {
+		t.Fatal(err)
+	}
+	gaps, err := bs.Gaps(Security{Conid: 1}, 1, Hour, schedule)
+	if err != nil || len(gaps) != 1 {
+		t.Fatalf("found gaps %v, %v, want one gap", gaps, err)
+	}
+	_, meta, err := bs.load(1, 1, Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta.Empty = append(meta.Empty, toMillis(gaps[0].Open))
+	if err := bs.writeMeta(1, 1, Hour, meta); err != nil {
+		t.Fatal(err)
+	}
+	gaps, err = bs.Gaps(Security{Conid: 1}, 1, Hour, schedule)
+	if err != nil || len(gaps) != 0 {
+		t.Errorf("found gaps %v, %v after the gap was repaired empty, want none", gaps, err)
+	}
+}
+
+func TestTradingPeriodStart(t *testing.T) {
+	schedule := weekdaySchedule(t)
+	loc := schedule.Location
+	tests := []struct {
+		now  time.Time
+		n    int
+		unit TimeUnit
+		want time.Time
+	}{
+		{time.Date(2020, 10, 12, 10, 0, 0, 0, loc), 2, Day, time.Date(2020, 10, 9, 9, 30, 0, 0, loc)},
+		{time.Date(2020, 10, 12, 10, 0, 0, 0, loc), 2, Hour, time.Date(2020, 10, 9, 14, 30, 0, 0, loc)},
+		{time.Date(2020, 10, 11, 12, 0, 0, 0, loc), 30, Min, time.Date(2020, 10, 9, 15, 30, 0, 0, loc)},
+		{time.Date(2020, 10, 14, 12, 0, 0, 0, loc), 1, Week, time.Date(2020, 10, 7, 12, 0, 0, 0, loc)},
+		{time.Date(2020, 10, 12, 8, 0, 0, 0, loc), 1, Week, time.Date(2020, 10, 5, 9, 30, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		if got := tradingPeriodStart(schedule, tt.now, tt.n, tt.unit, RegularHours); !got.Equal(tt.want) {
+			t.Errorf("tradingPeriodStart(%v, %d%s) = %v, want %v", tt.now, tt.n, tt.unit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package ib
+
+import (
+	"container/list"
+	"crypto/tls"
+	"log"
+	"strconv"
+	"sync"
+
+	resty "github.com/go-resty/resty/v2"
+)
+
+// DefaultMarketDataLines is the number of concurrent market data lines IB allows most accounts.
+const DefaultMarketDataLines = 100
+
+// SubscriptionManager tracks the market data lines opened on the brokerage server
+// and releases the least recently used line once the cap is reached.
+type SubscriptionManager struct {
+	mu       sync.Mutex
+	maxLines int
+	lines    map[int]*list.Element
+	lru      *list.List
+}
+
+// NewSubscriptionManager creates a subscription manager which keeps at most maxLines lines open.
+func NewSubscriptionManager(maxLines int) *SubscriptionManager {
+	return &SubscriptionManager{
+		maxLines: maxLines,
+		lines:    make(map[int]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// Subscriptions tracks the market data lines opened by snapshots.
+var Subscriptions = NewSubscriptionManager(DefaultMarketDataLines)
+
+// SetMaxLines changes the number of lines kept open, releasing lines over the new cap.
+func (sm *SubscriptionManager) SetMaxLines(maxLines int) {
+	sm.mu.Lock()
+	sm.maxLines = maxLines
+	evicted := sm.evict(0)
+	sm.mu.Unlock()
+	for _, conid := range evicted {
+		Unsubscribe(conid)
+	}
+}
+
+// MaxLines returns the number of lines kept open, 0 for no cap.
+func (sm *SubscriptionManager) MaxLines() int {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.maxLines
+}
+
+// evict removes least recently used lines until there is room for n more.
+// The caller must hold the lock and unsubscribe the returned contract ids.
+func (sm *SubscriptionManager) evict(n int) []int {
+	evicted := make([]int, 0)
+	for sm.maxLines > 0 && sm.lru.Len()+n > sm.maxLines {
+		oldest := sm.lru.Back()
+		conid := oldest.Value.(int)
+		sm.lru.Remove(oldest)
+		delete(sm.lines, conid)
+		evicted = append(evicted, conid)
+	}
+	return evicted
+}
+
+// Touch marks a line as used, opening room for it if it is new.
+// It reports whether the line was already open.
+func (sm *SubscriptionManager) Touch(conid int) bool {
+	sm.mu.Lock()
+	if e, ok := sm.lines[conid]; ok {
+		sm.lru.MoveToFront(e)
+		sm.mu.Unlock()
+		return true
+	}
+	evicted := sm.evict(1)
+	sm.lines[conid] = sm.lru.PushFront(conid)
+	sm.mu.Unlock()
+	for _, c := range evicted {
+		Unsubscribe(c)
+	}
+	return false
+}
+
+// IsOpen reports whether a line is open for a contract id.
+func (sm *SubscriptionManager) IsOpen(conid int) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	_, ok := sm.lines[conid]
+	return ok
+}
+
+// Open returns the contract ids with open lines, most recently used first.
+func (sm *SubscriptionManager) Open() []int {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	conids := make([]int, 0, sm.lru.Len())
+	for e := sm.lru.Front(); e != nil; e = e.Next() {
+		conids = append(conids, e.Value.(int))
+	}
+	return conids
+}
+
+// Release closes the line of a contract id.
+func (sm *SubscriptionManager) Release(conid int) {
+	sm.mu.Lock()
+	if e, ok := sm.lines[conid]; ok {
+		sm.lru.Remove(e)
+		delete(sm.lines, conid)
+	}
+	sm.mu.Unlock()
+	Unsubscribe(conid)
+}
+
+// ReleaseAll closes every line.
+func (sm *SubscriptionManager) ReleaseAll() {
+	sm.mu.Lock()
+	sm.lines = make(map[int]*list.Element)
+	sm.lru.Init()
+	sm.mu.Unlock()
+	UnsubscribeAll()
+}
+
+// Unsubscribe closes the market data line of a contract id on the brokerage server.
+// Prefer Subscriptions.Release to keep the subscription manager in sync.
+func Unsubscribe(conid int) {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	_, err := client.R().Get(base + "/api/iserver/marketdata/" + strconv.Itoa(conid) + "/unsubscribe")
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// UnsubscribeAll closes all market data lines on the brokerage server.
+// Prefer Subscriptions.ReleaseAll to keep the subscription manager in sync.
+func UnsubscribeAll() {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	_, err := client.R().Get(base + "/api/iserver/marketdata/unsubscribeall")
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// Unsubscribe closes the market data line of a security
+func (s Security) Unsubscribe() {
+	Subscriptions.Release(s.Conid)
+}
@@ -0,0 +1,169 @@
+package ib
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Value stores the value of a market data field as returned from the brokerage server.
+// Numbers are kept in their textual form and converted by the typed accessors.
+type Value string
+
+// Values stores every field of a snapshot keyed by field, including fields without a Snapshot struct field.
+type Values map[MarketDataField]Value
+
+// Raw returns the field holding the unformatted variant of a field, e.g. 78_raw for DailyPnL.
+func (f MarketDataField) Raw() MarketDataField {
+	return f + "_raw"
+}
+
+// newValue converts a raw json value to a value.
+func newValue(raw json.RawMessage) Value {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return Value(s)
+	}
+	return Value(raw)
+}
+
+// String returns the value as text.
+func (v Value) String() string {
+	return string(v)
+}
+
+// ParsedValue stores a value parsed from the formats IB uses for market data.
+type ParsedValue struct {
+	// Number is the numeric value with suffixes and grouping removed, valid if IsNumber is set.
+	Number   float64
+	IsNumber bool
+	// PreviousClose is set for prices prefixed with C, which are the previous close as there were no trades today.
+	PreviousClose bool
+	// Halted is set for prices prefixed with H, the security is halted.
+	Halted bool
+	// Percent is set for values suffixed with %, Number holds the percentage.
+	Percent bool
+	// Placeholder is set for values which only hold a placeholder like - or N/A.
+	Placeholder bool
+}
+
+// magnitudes are the suffixes IB abbreviates large numbers with.
+var magnitudes = map[byte]float64{
+	'K': 1e3,
+	'M': 1e6,
+	'B': 1e9,
+	'T': 1e12,
+}
+
+// placeholders are the values IB returns instead of a number when there is no data.
+var placeholders = map[string]bool{
+	"":    true,
+	"-":   true,
+	"--":  true,
+	"H":   true,
+	"C":   true,
+	"N/A": true,
+}
+
+// Parse parses a value in the formats IB uses for market data, e.g. C123.45, 1.23M, 12.5% and 1,234,567.
+// Parsing never fails, values which aren't numbers only have their flags set.
+func (v Value) Parse() ParsedValue {
+	pv := ParsedValue{}
+	s := strings.TrimSpace(string(v))
+	if placeholders[s] {
+		pv.Placeholder = true
+		return pv
+	}
+	switch s[0] {
+	case 'C':
+		pv.PreviousClose = true
+		s = s[1:]
+	case 'H':
+		pv.Halted = true
+		s = s[1:]
+	}
+	s = strings.Replace(s, ",", "", -1)
+	if strings.HasSuffix(s, "%") {
+		pv.Percent = true
+		s = strings.TrimSuffix(s, "%")
+	}
+	multiplier := 1.0
+	if len(s) > 0 {
+		if m, ok := magnitudes[s[len(s)-1]]; ok {
+			multiplier = m
+			s = s[:len(s)-1]
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return pv
+	}
+	pv.Number = f * multiplier
+	pv.IsNumber = true
+	return pv
+}
+
+// Float returns the value as a float.
+func (v Value) Float() (float64, bool) {
+	pv := v.Parse()
+	return pv.Number, pv.IsNumber
+}
+
+// Int returns the value as an integer, truncating fractions.
+func (v Value) Int() (int64, bool) {
+	pv := v.Parse()
+	return int64(pv.Number), pv.IsNumber
+}
+
+// Time returns the value as a time, from either epoch milliseconds or a YYYYMMDD date.
+func (v Value) Time() (time.Time, bool) {
+	s := string(v)
+	if len(s) == 8 {
+		t, err := time.Parse("20060102", s)
+		if err == nil {
+			return t, true
+		}
+	}
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, ms*int64(time.Millisecond)), true
+}
+
+// Field returns the value of a field, preferring its unformatted variant if present.
+func (s Snapshot) Field(f MarketDataField) (Value, bool) {
+	if v, ok := s.Values[f.Raw()]; ok {
+		return v, true
+	}
+	v, ok := s.Values[f]
+	return v, ok
+}
+
+// Float returns the value of a field as a float.
+func (s Snapshot) Float(f MarketDataField) (float64, bool) {
+	v, ok := s.Field(f)
+	if !ok {
+		return 0, false
+	}
+	return v.Float()
+}
+
+// Int returns the value of a field as an integer.
+func (s Snapshot) Int(f MarketDataField) (int64, bool) {
+	v, ok := s.Field(f)
+	if !ok {
+		return 0, false
+	}
+	return v.Int()
+}
+
+// Time returns the value of a field as a time.
+func (s Snapshot) Time(f MarketDataField) (time.Time, bool) {
+	v, ok := s.Field(f)
+	if !ok {
+		return time.Time{}, false
+	}
+	return v.Time()
+}
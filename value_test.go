@@ -0,0 +1,69 @@
+package ib
+
+import "testing"
+
+func TestValueParse(t *testing.T) {
+	tests := []struct {
+		value Value
+		want  ParsedValue
+	}{
+		{"123.45", ParsedValue{Number: 123.45, IsNumber: true}},
+		{"-0.5", ParsedValue{Number: -0.5, IsNumber: true}},
+		{"C123.45", ParsedValue{Number: 123.45, IsNumber: true, PreviousClose: true}},
+		{"H99", ParsedValue{Number: 99, IsNumber: true, Halted: true}},
+		{"1,234,567", ParsedValue{Number: 1234567, IsNumber: true}},
+		{"12.5%", ParsedValue{Number: 12.5, IsNumber: true, Percent: true}},
+		{"-1.25%", ParsedValue{Number: -1.25, IsNumber: true, Percent: true}},
+		{"1.5K", ParsedValue{Number: 1500, IsNumber: true}},
+		{"2.25M", ParsedValue{Number: 2250000, IsNumber: true}},
+		{"3B", ParsedValue{Number: 3e9, IsNumber: true}},
+		{"1.1T", ParsedValue{Number: 1.1e12, IsNumber: true}},
+		{" 42 ", ParsedValue{Number: 42, IsNumber: true}},
+		{"", ParsedValue{Placeholder: true}},
+		{"-", ParsedValue{Placeholder: true}},
+		{"--", ParsedValue{Placeholder: true}},
+		{"N/A", ParsedValue{Placeholder: true}},
+		{"C", ParsedValue{Placeholder: true}},
+		{"H", ParsedValue{Placeholder: true}},
+		{"Cabc", ParsedValue{PreviousClose: true}},
+		{"Hx%", ParsedValue{Halted: true, Percent: true}},
+		{"AAPL", ParsedValue{}},
+	}
+	for _, tt := range tests {
+		got := tt.value.Parse()
+		if got != tt.want {
+			t.Errorf("Value(%q).Parse() = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestValueAccessors(t *testing.T) {
+	if f, ok := Value("1.5M").Float(); !ok || f != 1.5e6 {
+		t.Errorf("Float() = %v, %v, want 1.5e6, true", f, ok)
+	}
+	if n, ok := Value("12.9").Int(); !ok || n != 12 {
+		t.Errorf("Int() = %v, %v, want 12, true", n, ok)
+	}
+	if _, ok := Value("-").Float(); ok {
+		t.Error("Float() of a placeholder reported a number")
+	}
+	if tm, ok := Value("20201005").Time(); !ok || tm.Year() != 2020 || tm.Month() != 10 || tm.Day() != 5 {
+		t.Errorf("Time() of a date = %v, %v", tm, ok)
+	}
+	if tm, ok := Value("1601856000000").Time(); !ok || tm.Unix() != 1601856000 {
+		t.Errorf("Time() of epoch milliseconds = %v, %v", tm, ok)
+	}
+}
+
+func TestSnapshotFieldPrefersRaw(t *testing.T) {
+	s := Snapshot{Values: Values{
+		DailyPnL:       "1.2K",
+		DailyPnL.Raw(): "1234.5",
+	}}
+	if f, ok := s.Float(DailyPnL); !ok || f != 1234.5 {
+		t.Errorf("Float(DailyPnL) = %v, %v, want 1234.5, true", f, ok)
+	}
+	if _, ok := s.Field(BidPrice); ok {
+		t.Error("Field(BidPrice) reported a field which isn't present")
+	}
+}
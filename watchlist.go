@@ -0,0 +1,177 @@
+package ib
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"log"
+	"net/url"
+
+	resty "github.com/go-resty/resty/v2"
+)
+
+// WatchlistSummary stores the name and id of a watchlist.
+type WatchlistSummary struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Modified int64  `json:"modified"`
+	ReadOnly bool   `json:"read_only"`
+	IsOpen   bool   `json:"is_open"`
+	Type     string `json:"type"`
+}
+
+type watchlistsResponse struct {
+	Data struct {
+		UserLists []WatchlistSummary `json:"user_lists"`
+	} `json:"data"`
+}
+
+// Watchlists retrieves all watchlists created by the user
+func Watchlists() []WatchlistSummary {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/iserver/watchlists?SC=USER_WATCHLIST")
+	if err != nil {
+		log.Panic(err)
+	}
+	wr := watchlistsResponse{}
+	err = json.Unmarshal(resp.Body(), &wr)
+	if err != nil {
+		log.Panic(err)
+	}
+	return wr.Data.UserLists
+}
+
+// WatchlistInstrument stores an instrument of a watchlist.
+type WatchlistInstrument struct {
+	Conid       int    `json:"conid"`
+	Name        string `json:"name"`
+	FullName    string `json:"fullName"`
+	AssetClass  string `json:"assetClass"`
+	Ticker      string `json:"ticker"`
+	ChineseName string `json:"chineseName,omitempty"`
+}
+
+// Watchlist stores a watchlist and its instruments.
+type Watchlist struct {
+	ID          string                `json:"id"`
+	Hash        string                `json:"hash"`
+	Name        string                `json:"name"`
+	ReadOnly    bool                  `json:"readOnly"`
+	Instruments []WatchlistInstrument `json:"instruments"`
+}
+
+// GetWatchlist retrieves a watchlist by id
+func GetWatchlist(id string) Watchlist {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().Get(base + "/api/iserver/watchlist?id=" + url.QueryEscape(id))
+	if err != nil {
+		log.Panic(err)
+	}
+	watchlist := Watchlist{}
+	err = json.Unmarshal(resp.Body(), &watchlist)
+	if err != nil {
+		log.Panic(err)
+	}
+	return watchlist
+}
+
+type watchlistRow struct {
+	C int `json:"C"`
+}
+
+type createWatchlistRequest struct {
+	ID   string         `json:"id"`
+	Name string         `json:"name"`
+	Rows []watchlistRow `json:"rows"`
+}
+
+// CreateWatchlist creates a watchlist of contract ids.
+// Creating a watchlist with an existing id replaces it.
+func CreateWatchlist(id string, name string, conids []int) Watchlist {
+	body := createWatchlistRequest{
+		ID:   id,
+		Name: name,
+		Rows: make([]watchlistRow, 0),
+	}
+	for _, conid := range conids {
+		body.Rows = append(body.Rows, watchlistRow{C: conid})
+	}
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.R().SetBody(body).Post(base + "/api/iserver/watchlist")
+	if err != nil {
+		log.Panic(err)
+	}
+	watchlist := Watchlist{}
+	err = json.Unmarshal(resp.Body(), &watchlist)
+	if err != nil {
+		log.Panic(err)
+	}
+	return watchlist
+}
+
+// DeleteWatchlist deletes a watchlist by id
+func DeleteWatchlist(id string) {
+	client := resty.New()
+	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	_, err := client.R().Delete(base + "/api/iserver/watchlist?id=" + url.QueryEscape(id))
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// Conids returns the contract ids of a watchlists instruments in order.
+func (w Watchlist) Conids() []int {
+	conids := make([]int, 0)
+	for _, i := range w.Instruments {
+		conids = append(conids, i.Conid)
+	}
+	return conids
+}
+
+// Securities creates security objects from all instruments of a watchlist
+func (w Watchlist) Securities(ba BrokerAccount) []Security {
+	securities := make([]Security, 0)
+	for _, i := range w.Instruments {
+		securities = append(securities, Security{
+			Broker: ba,
+			Conid:  i.Conid,
+		})
+	}
+	return securities
+}
+
+// SyncWatchlist makes a server watchlist contain exactly the given contract ids, creating it if it doesn't exist.
+// It reports whether the watchlist had to be changed, a watchlist which only differs in order is left as it is.
+func SyncWatchlist(id string, name string, conids []int) bool {
+	for _, summary := range Watchlists() {
+		if summary.ID != id {
+			continue
+		}
+		current := GetWatchlist(id).Conids()
+		if summary.Name == name && equalConids(current, conids) {
+			return false
+		}
+		break
+	}
+	CreateWatchlist(id, name, conids)
+	return true
+}
+
+// equalConids reports whether two lists contain the same contract ids, regardless of their order.
+func equalConids(a []int, b []int) bool {
+	set := make(map[int]bool, len(a))
+	for _, conid := range a {
+		set[conid] = true
+	}
+	for _, conid := range b {
+		if !set[conid] {
+			return false
+		}
+	}
+	for _, conid := range b {
+		delete(set, conid)
+	}
+	return len(set) == 0
+}
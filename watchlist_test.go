@@ -0,0 +1,23 @@
+package ib
+
+import "testing"
+
+func TestEqualConids(t *testing.T) {
+	tests := []struct {
+		a     []int
+		b     []int
+		equal bool
+	}{
+		{[]int{1, 2, 3}, []int{1, 2, 3}, true},
+		{[]int{1, 2, 3}, []int{3, 1, 2}, true},
+		{[]int{}, nil, true},
+		{[]int{1, 2}, []int{1, 2, 3}, false},
+		{[]int{1, 2, 3}, []int{1, 2}, false},
+		{[]int{1, 2}, []int{1, 3}, false},
+	}
+	for _, tt := range tests {
+		if equal := equalConids(tt.a, tt.b); equal != tt.equal {
+			t.Errorf("equalConids(%v, %v) = %v, want %v", tt.a, tt.b, equal, tt.equal)
+		}
+	}
+}